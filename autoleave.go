@@ -0,0 +1,177 @@
+package pammy
+
+import (
+	"time"
+)
+
+// AutoLeave configures when the bots leave their voice channels by themselves, a zero timeout disables it.
+type AutoLeave struct {
+	// EmptyTimeout is how long a bot stays paused in a voice channel without listeners.
+	EmptyTimeout time.Duration
+	// IdleTimeout is how long a bot stays in a voice channel waiting for tracks.
+	IdleTimeout time.Duration
+}
+
+// idleBot keeps track of a bot that has no listeners or is waiting for tracks.
+type idleBot struct {
+	emptySince   time.Time
+	waitingSince time.Time
+	// paused is true if the bot has been paused because nobody is listening.
+	paused bool
+}
+
+// CheckListeners pauses the bot of the guild when nobody is listening and resumes it when someone joins again.
+func (h *Hub) CheckListeners(guildID string) {
+	if h.autoLeave.EmptyTimeout <= 0 {
+		return
+	}
+
+	bot, ok := h.GetBot(guildID)
+	if !ok {
+		return
+	}
+
+	channelID := bot.VoiceChannelID()
+	if channelID == "" {
+		return
+	}
+
+	empty := len(voiceChannelListeners(h.dg.State, guildID, channelID)) == 0
+
+	// the bot is paused or resumed without holding h.mu since it may wait for the play loop
+	h.mu.Lock()
+	idle, ok := h.idle[guildID]
+	if !ok {
+		idle = &idleBot{}
+		h.idle[guildID] = idle
+	}
+
+	var pause, resume bool
+	switch {
+	case empty && idle.emptySince.IsZero():
+		idle.emptySince = time.Now()
+		pause = true
+	case !empty && !idle.emptySince.IsZero():
+		resume = idle.paused
+		idle.emptySince = time.Time{}
+		idle.paused = false
+	}
+	h.mu.Unlock()
+
+	if resume && bot.State() == BotStatePaused {
+		bot.Resume()
+	}
+
+	if !pause {
+		return
+	}
+
+	paused := bot.Pause() == nil
+
+	h.mu.Lock()
+	stillEmpty := h.idle[guildID] == idle && !idle.emptySince.IsZero()
+	if stillEmpty {
+		idle.paused = paused
+	}
+	h.mu.Unlock()
+
+	// someone joined while pausing
+	if paused && !stillEmpty {
+		bot.Resume()
+	}
+}
+
+// Watch makes the bots leave their voice channels after being idle for the timeouts of the auto leave config.
+// It checks the bots every interval until the hub is closed.
+func (h *Hub) Watch(interval time.Duration) {
+	if h.autoLeave.EmptyTimeout <= 0 && h.autoLeave.IdleTimeout <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			h.leaveIdleBots()
+		case <-h.closeCh:
+			return
+		}
+	}
+}
+
+func (h *Hub) leaveIdleBots() {
+	bots := h.copyBots()
+
+	// voice state updates can be missed, e.g. when the bot rejoins after restart
+	for guildID := range bots {
+		h.CheckListeners(guildID)
+	}
+
+	now := time.Now()
+	for guildID, bot := range bots {
+		// the bot is queried without holding h.mu since it may wait for the play loop
+		inVoiceChannel := bot.VoiceChannelID() != ""
+		waiting := bot.State() == BotStateWaitForTrack
+
+		msg := h.expireIdle(guildID, bot, inVoiceChannel, waiting, now)
+		if msg == "" {
+			continue
+		}
+
+		if textChannelID := bot.TextChannelID(); textChannelID != "" {
+			h.dg.ChannelMessageSend(textChannelID, msg)
+		}
+
+		bot.Close()
+		h.RemoveBot(guildID)
+	}
+}
+
+// expireIdle updates the idle times of the bot then returns the message to leave with if it has been idle for too long.
+// The bot is removed from the hub right away so that it is not closed twice by a command.
+func (h *Hub) expireIdle(guildID string, bot *Bot, inVoiceChannel, waiting bool, now time.Time) string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// the bot has been closed or replaced meanwhile
+	if h.bots[guildID] != bot {
+		return ""
+	}
+
+	// bots that are not in a voice channel (e.g. restored without resuming) keep their queue
+	if !inVoiceChannel {
+		delete(h.idle, guildID)
+		return ""
+	}
+
+	idle, ok := h.idle[guildID]
+	if !ok {
+		idle = &idleBot{}
+		h.idle[guildID] = idle
+	}
+
+	if waiting {
+		if idle.waitingSince.IsZero() {
+			idle.waitingSince = now
+		}
+	} else {
+		idle.waitingSince = time.Time{}
+	}
+
+	var msg string
+	switch {
+	case h.autoLeave.EmptyTimeout > 0 && !idle.emptySince.IsZero() && now.Sub(idle.emptySince) >= h.autoLeave.EmptyTimeout:
+		msg = "Nobody is listening, seeya!"
+	case h.autoLeave.IdleTimeout > 0 && !idle.waitingSince.IsZero() && now.Sub(idle.waitingSince) >= h.autoLeave.IdleTimeout:
+		msg = "No more tracks to play, seeya!"
+	default:
+		return ""
+	}
+
+	delete(h.bots, guildID)
+	delete(h.idle, guildID)
+
+	return msg
+}
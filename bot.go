@@ -4,7 +4,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"log"
 	"math"
 	"math/rand"
@@ -21,12 +20,80 @@ var (
 	ErrEmptyTracks              = errors.New("empty tracks")
 	ErrTrackNotFound            = errors.New("track not found")
 	ErrCannotRemovePlayingTrack = errors.New("cannot remove the playing track")
+	ErrNoUpcomingTracks         = errors.New("no upcoming tracks")
+	ErrNotShuffled              = errors.New("tracks are not shuffled")
+	ErrNotPlaying               = errors.New("no music is playing")
+	ErrSeekOutOfRange           = errors.New("seek position is out of range")
+	ErrInvalidVolume            = errors.New("volume must be between 0 and 200")
+	ErrInvalidCrossfade         = errors.New("crossfade must be between 0 and 12 seconds")
+	ErrVoiceDisconnected        = errors.New("cannot reconnect to voice channel")
+	ErrCannotSeekLive           = errors.New("cannot seek in a live stream")
+
+	errSkipped = errors.New("track skipped")
+	errStopped = errors.New("player stopped")
+)
+
+const (
+	DefaultVolume = 100
+	MaxVolume     = 200
+
+	MaxCrossfade = 12 * time.Second
+
+	// reconnectAttempts is the number of times to rejoin the voice channel after the voice connection dropped.
+	reconnectAttempts = 3
+	// maxReconnects is the number of times the voice connection can drop while playing a track before the player stops,
+	// so that a connection that keeps dropping right after rejoining does not reconnect forever.
+	maxReconnects = 3
+
+	// maxStreamRecoveries is the number of times to get a new stream URL when the stream of a track ends early.
+	maxStreamRecoveries = 3
+	// earlyEndTolerance is how much earlier than the track duration a stream can end normally.
+	earlyEndTolerance = 5 * time.Second
+)
+
+// TrackSource tells how a track was added to the queue.
+type TrackSource string
+
+const (
+	TrackSourceManual   TrackSource = "manual"
+	TrackSourceAutoplay TrackSource = "autoplay"
+	TrackSourcePlaylist TrackSource = "playlist"
 )
 
 type Track struct {
 	ID       string
 	Name     string
 	Duration time.Duration
+	// Live is true for live streams, they have no duration and play until skipped.
+	Live bool
+
+	RequesterID   string
+	RequesterName string
+	RequestedAt   time.Time
+	Source        TrackSource
+}
+
+// RequestInfo returns who requested the track, how and when.
+func (t Track) RequestInfo() string {
+	var info string
+	switch {
+	case t.Source == TrackSourceAutoplay:
+		info = "autoplay"
+	case t.RequesterName != "":
+		info = t.RequesterName
+	default:
+		info = "unknown"
+	}
+
+	if t.Source == TrackSourcePlaylist {
+		info += " via playlist"
+	}
+
+	if !t.RequestedAt.IsZero() {
+		info += " at " + t.RequestedAt.Format("15:04")
+	}
+
+	return info
 }
 
 type BotState uint
@@ -37,27 +104,108 @@ const (
 	BotStatePaused
 )
 
+// LoopMode controls what the player does when a track ends.
+// Looping takes precedence over auto discovering the next track.
+type LoopMode uint
+
+const (
+	LoopModeOff LoopMode = iota
+	LoopModeTrack
+	LoopModeQueue
+)
+
+func (m LoopMode) String() string {
+	switch m {
+	case LoopModeTrack:
+		return "track"
+	case LoopModeQueue:
+		return "queue"
+	default:
+		return "off"
+	}
+}
+
 type Bot struct {
 	mu sync.RWMutex
 
 	guidID         string
 	voiceChannelID string
 	textChannelID  string
+	// lastInvokerID is the member who ran the last command, the bot follows them to other voice channels.
+	lastInvokerID string
 
 	tracks                []*Track
 	currentTrackIdx       int
 	state                 BotState
 	autoDiscoverNextTrack bool
-
-	errCh  chan error
-	skipCh chan chan struct{}
-	stopCh chan struct{}
+	loopMode              LoopMode
+	volume                int      // percent of the original volume
+	filters               []string // names of the active audio filters
+	crossfade             time.Duration
+	settings              Settings
+
+	// skipVotes are the users who voted to skip the playing track.
+	skipVotes map[string]bool
+
+	// originalTracks holds the track order before shuffling, nil when tracks are not shuffled.
+	originalTracks []*Track
+
+	errCh chan error
+	// skipCh wakes up the play loop to skip the playing track after skipped is set.
+	skipCh chan struct{}
+	// seekCh wakes up the play loop to restart the playing track at the position of seeking.
+	seekCh chan struct{}
+	// stopCh is closed to stop the play loop and loopDone is closed when it has returned.
+	// Each play loop has its own channels, they are nil when no play loop is running.
+	stopCh   chan struct{}
+	loopDone chan struct{}
+
+	// skipped is set when the playing track is skipped, the play loop then plays skipTo.
+	// skipTo is nil when skipping past the last track.
+	skipped bool
+	skipTo  *Track
+
+	// seeking is the pending seek request, it is dropped if its track is not playing anymore.
+	seeking *seekRequest
 
 	ytClient   *youtube.Client
 	dg         *discordgo.Session
 	vc         *discordgo.VoiceConnection
 	streamSess *dca.StreamingSession
-	encodeSess *dca.EncodeSession
+	encoder    *opusEncoder
+	mixer      *mixer
+	// streamTrack is the track of the current stream session.
+	streamTrack *Track
+
+	// handover is the source of the next track that has been faded in by the mixer of the previous track.
+	// It is only accessed by the play loop.
+	handover *pcmSource
+
+	// prefetched is the next track being resolved in the background while the current track is playing.
+	prefetched *prefetchedTrack
+
+	// resumeTrack is played from resumePosition instead of the beginning, used when restoring a snapshot.
+	resumeTrack    *Track
+	resumePosition time.Duration
+
+	// streamOffset is the position in the playing track where the current stream session started.
+	streamOffset time.Duration
+	// streamSpeed is the playback speed factor of the audio filters of the current stream session.
+	streamSpeed float64
+}
+
+type prefetchedTrack struct {
+	track *Track
+	done  chan struct{}
+
+	title     string
+	streamURL string
+	err       error
+}
+
+type seekRequest struct {
+	track    *Track
+	position time.Duration
 }
 
 func NewBot(guidID string, dg *discordgo.Session, ytClient *youtube.Client, errCh chan error) *Bot {
@@ -66,14 +214,90 @@ func NewBot(guidID string, dg *discordgo.Session, ytClient *youtube.Client, errC
 		tracks:          nil,
 		currentTrackIdx: 0,
 		state:           BotStateWaitForTrack,
+		volume:          DefaultVolume,
 		errCh:           errCh,
-		skipCh:          make(chan chan struct{}),
-		stopCh:          make(chan struct{}),
+		skipCh:          make(chan struct{}, 1),
+		seekCh:          make(chan struct{}, 1),
 		ytClient:        ytClient,
 		dg:              dg,
 	}
 }
 
+// BotSnapshot is the state of a bot that is persisted across restarts.
+type BotSnapshot struct {
+	GuildID               string
+	VoiceChannelID        string
+	TextChannelID         string
+	Tracks                []*Track
+	CurrentTrackIdx       int
+	Playing               bool
+	Position              time.Duration
+	AutoDiscoverNextTrack bool
+	LoopMode              LoopMode
+	Volume                int
+	Filters               []string
+	Crossfade             time.Duration
+}
+
+func (b *Bot) Snapshot() BotSnapshot {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	snapshot := BotSnapshot{
+		GuildID:               b.guidID,
+		VoiceChannelID:        b.voiceChannelID,
+		TextChannelID:         b.textChannelID,
+		Tracks:                b.copyTracks(),
+		CurrentTrackIdx:       b.currentTrackIdx,
+		Playing:               b.state != BotStateWaitForTrack,
+		AutoDiscoverNextTrack: b.autoDiscoverNextTrack,
+		LoopMode:              b.loopMode,
+		Volume:                b.volume,
+		Filters:               append([]string(nil), b.filters...),
+		Crossfade:             b.crossfade,
+	}
+
+	if snapshot.Playing {
+		snapshot.Position = b.position()
+	}
+
+	return snapshot
+}
+
+// Restore restores the tracks and settings from the snapshot without joining the voice channel.
+// The track that was playing in the snapshot continues from its last position when it is played again.
+func (b *Bot) Restore(snapshot BotSnapshot) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.textChannelID = snapshot.TextChannelID
+	b.tracks = snapshot.Tracks
+	b.currentTrackIdx = snapshot.CurrentTrackIdx
+	b.autoDiscoverNextTrack = snapshot.AutoDiscoverNextTrack
+	b.loopMode = snapshot.LoopMode
+	b.volume = snapshot.Volume
+	b.filters = snapshot.Filters
+	b.crossfade = snapshot.Crossfade
+
+	if b.crossfade < 0 || b.crossfade > MaxCrossfade {
+		b.crossfade = 0
+	}
+
+	// snapshots saved before volume control have zero volume
+	if b.volume <= 0 || b.volume > MaxVolume {
+		b.volume = DefaultVolume
+	}
+
+	if b.currentTrackIdx < 0 || b.currentTrackIdx > len(b.tracks) {
+		b.currentTrackIdx = 0
+	}
+
+	if snapshot.Playing && b.currentTrackIdx < len(b.tracks) {
+		b.resumeTrack = b.tracks[b.currentTrackIdx]
+		b.resumePosition = snapshot.Position
+	}
+}
+
 func (b *Bot) JoinVoiceChannel(channelID string) error {
 	b.mu.Lock()
 	defer b.mu.Unlock()
@@ -89,216 +313,1191 @@ func (b *Bot) JoinVoiceChannel(channelID string) error {
 	return nil
 }
 
-func (b *Bot) Close() {
-	b.mu.Lock()
-	defer b.mu.Unlock()
-
-	if b.state != BotStateWaitForTrack {
-		b.stopCh <- struct{}{}
+func (b *Bot) Close() {
+	b.stopPlaying()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.vc != nil {
+		b.vc.Disconnect()
+		b.vc.Close()
+	}
+
+	if b.errCh != nil {
+		close(b.errCh)
+	}
+}
+
+// MoveVoiceChannel moves the bot to another voice channel of the guild without interrupting the playing track.
+func (b *Bot) MoveVoiceChannel(channelID string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.vc == nil {
+		return ErrNotInVoiceChannel
+	}
+
+	err := b.vc.ChangeChannel(channelID, false, true)
+	if err != nil {
+		return err
+	}
+
+	b.voiceChannelID = channelID
+	if b.state != BotStateWaitForTrack {
+		b.vc.Speaking(true)
+	}
+
+	return nil
+}
+
+func (b *Bot) play(stop, done chan struct{}) {
+	defer close(done)
+
+	if b.vc == nil {
+		b.mu.Lock()
+		b.endLoop(stop)
+		b.mu.Unlock()
+		b.sendError(ErrNotInVoiceChannel)
+		return
+	}
+
+	b.vc.Speaking(true)
+	defer func() {
+		b.mu.Lock()
+		b.endLoop(stop)
+		// another play loop may have started after this one ran out of tracks
+		if b.stopCh == nil {
+			b.vc.Speaking(false)
+		}
+		b.mu.Unlock()
+
+		if b.handover != nil {
+			b.handover.Close()
+			b.handover = nil
+		}
+	}()
+
+	for {
+		b.mu.Lock()
+		if isClosed(stop) {
+			b.mu.Unlock()
+			return
+		}
+		// the track was skipped before it started playing
+		if b.skipped {
+			b.applySkip()
+		}
+		if b.currentTrackIdx >= len(b.tracks) {
+			// set the state while holding the lock so that a track added at this moment starts a new play loop
+			b.endLoop(stop)
+			b.mu.Unlock()
+			return
+		}
+		track := b.tracks[b.currentTrackIdx]
+		b.skipVotes = nil
+		b.mu.Unlock()
+
+		var (
+			title, streamURL string
+			err              error
+		)
+
+		src := b.takeHandover(track)
+		if src != nil {
+			title, streamURL = src.title, src.streamURL
+		} else {
+			title, streamURL, err = b.resolveTrack(track)
+		}
+
+		if isClosed(stop) {
+			if src != nil {
+				src.Close()
+			}
+			return
+		}
+
+		if err != nil {
+			b.sendError(err)
+		} else {
+			_, sendErr := b.dg.ChannelMessageSend(b.textChannelID, fmt.Sprintf("Playing `%s`", title))
+			if sendErr != nil {
+				log.Println(sendErr)
+			}
+		}
+
+		if err != nil {
+			b.dg.ChannelMessageSend(b.textChannelID, fmt.Sprintf("Cannot play `%s`, skipping...", track.Name))
+		} else {
+			b.mu.Lock()
+			b.prefetchNext()
+			b.mu.Unlock()
+
+			var stopped bool
+			stopped, err = b.playStream(stop, track, title, streamURL, src)
+			if err == ErrVoiceDisconnected {
+				b.dg.ChannelMessageSend(b.textChannelID, "Cannot reconnect to voice channel, stopped playing")
+				b.sendError(err)
+				return
+			}
+			if stopped {
+				return
+			}
+			if err != nil {
+				log.Printf("cannot play %s: %v", track.ID, err)
+				b.dg.ChannelMessageSend(b.textChannelID, fmt.Sprintf("Cannot play `%s`, skipping...", track.Name))
+			}
+		}
+
+		b.mu.Lock()
+		repeatTrack := b.loopMode == LoopModeTrack && !b.skipped && err == nil
+		next := b.currentTrackIdx + 1
+		if b.skipped {
+			next = b.playingIdx()
+		}
+		discover := next >= len(b.tracks) && b.autoDiscoverNextTrack && b.loopMode != LoopModeQueue && !repeatTrack
+		b.mu.Unlock()
+
+		if discover {
+			discoverErr := b.discoverNextTrack()
+			if discoverErr != nil {
+				log.Println("cannot discover next track: ", discoverErr)
+				b.dg.ChannelMessageSend(b.textChannelID, "Cannot discover next music")
+			}
+		}
+
+		b.mu.Lock()
+		if isClosed(stop) {
+			b.mu.Unlock()
+			return
+		}
+		// the track may have been skipped while discovering
+		if b.skipped {
+			b.applySkip()
+		} else if b.loopMode != LoopModeTrack || err != nil {
+			b.advance()
+		}
+		b.mu.Unlock()
+	}
+}
+
+// advance moves the current track index to the next track, b.mu must be held.
+// The queue starts over after the last track when looping the queue.
+func (b *Bot) advance() {
+	b.currentTrackIdx++
+	if b.currentTrackIdx >= len(b.tracks) && b.loopMode == LoopModeQueue {
+		b.currentTrackIdx = 0
+	}
+}
+
+// applySkip moves the current track index to the track that the playing track has been skipped to, b.mu must be held.
+func (b *Bot) applySkip() {
+	b.currentTrackIdx = b.playingIdx()
+	b.skipped = false
+	b.skipTo = nil
+
+	if b.currentTrackIdx >= len(b.tracks) && b.loopMode == LoopModeQueue {
+		b.currentTrackIdx = 0
+	}
+}
+
+// endLoop sets the state to waiting for tracks unless the play loop of stop has been stopped or replaced, b.mu must be held.
+func (b *Bot) endLoop(stop chan struct{}) {
+	if b.stopCh != stop {
+		return
+	}
+
+	b.state = BotStateWaitForTrack
+	b.stopCh = nil
+	b.loopDone = nil
+}
+
+// stopPlaying stops the play loop and waits until it has returned.
+// b.mu must not be held since the play loop needs it to return.
+func (b *Bot) stopPlaying() {
+	b.mu.Lock()
+	stop, done := b.stopCh, b.loopDone
+	b.stopCh = nil
+	b.loopDone = nil
+	b.mu.Unlock()
+
+	if stop == nil {
+		return
+	}
+
+	close(stop)
+	<-done
+}
+
+func isClosed(ch chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+// endedEarly reports whether the stream of the track ended before the end of the track.
+// A live stream always ends early since it plays until skipped.
+func endedEarly(track *Track, position time.Duration) bool {
+	return track.Live || track.Duration > 0 && track.Duration-position > earlyEndTolerance
+}
+
+// reconnect rejoins the voice channel after the voice connection dropped, e.g. when the voice server changes.
+// It returns stopped if the bot is stopped while waiting to retry or ErrVoiceDisconnected if it cannot rejoin.
+func (b *Bot) reconnect(stop chan struct{}) (stopped bool, err error) {
+	b.mu.RLock()
+	channelID := b.voiceChannelID
+	b.mu.RUnlock()
+
+	b.dg.ChannelMessageSend(b.textChannelID, "Lost voice connection, reconnecting...")
+
+	delay := time.Second
+	for attempt := 0; attempt < reconnectAttempts; attempt++ {
+		var vc *discordgo.VoiceConnection
+		vc, err = b.dg.ChannelVoiceJoin(b.guidID, channelID, false, true)
+		if err == nil {
+			b.mu.Lock()
+			b.vc = vc
+			b.mu.Unlock()
+
+			if isClosed(stop) {
+				return true, nil
+			}
+
+			vc.Speaking(true)
+			return false, nil
+		}
+
+		if attempt == reconnectAttempts-1 {
+			break
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-stop:
+			return true, nil
+		}
+		delay *= 2
+	}
+
+	log.Printf("cannot rejoin voice channel in guild %s: %v", b.guidID, err)
+
+	return false, ErrVoiceDisconnected
+}
+
+// resolveTrack returns the video title and the audio stream URL of the track.
+// The prefetched result is used if the track has been resolved in the background.
+func (b *Bot) resolveTrack(track *Track) (title, streamURL string, err error) {
+	b.mu.Lock()
+	p := b.prefetched
+	b.prefetched = nil
+	b.mu.Unlock()
+
+	if p != nil && p.track == track {
+		<-p.done
+		if p.err == nil {
+			return p.title, p.streamURL, nil
+		}
+	}
+
+	return b.fetchTrack(track)
+}
+
+// fetchTrack gets the video title and a fresh audio stream URL of the track from YouTube.
+func (b *Bot) fetchTrack(track *Track) (title, streamURL string, err error) {
+	video, err := b.ytClient.GetVideo(track.ID)
+	if err != nil {
+		return "", "", err
+	}
+
+	b.mu.RLock()
+	bitrate := b.channelBitrate()
+	b.mu.RUnlock()
+
+	streamURL, err = b.ytClient.GetAudioStreamURL(video, bitrate)
+	if err != nil {
+		return "", "", err
+	}
+
+	return video.Title, streamURL, nil
+}
+
+// channelBitrate returns the bitrate of the voice channel in bits per second, 0 if it is unknown. b.mu must be held.
+func (b *Bot) channelBitrate() int {
+	if b.voiceChannelID == "" {
+		return 0
+	}
+
+	channel, err := b.dg.State.Channel(b.voiceChannelID)
+	if err != nil {
+		return 0
+	}
+
+	return channel.Bitrate
+}
+
+// nextIdx returns the index of the track that will be played after the current one,
+// it is the length of the tracks if there is none. b.mu must be held.
+func (b *Bot) nextIdx() int {
+	next := b.currentTrackIdx + 1
+	switch {
+	case b.skipped:
+		next = b.playingIdx()
+	case b.loopMode == LoopModeTrack:
+		next = b.currentTrackIdx
+	}
+
+	if next >= len(b.tracks) && b.loopMode == LoopModeQueue {
+		next = 0
+	}
+
+	return next
+}
+
+// prefetchNext resolves the track that will be played after the current one in the background,
+// so that the next track starts without waiting for YouTube. b.mu must be held.
+// It must be called again whenever the queue changes so that a prefetched track that is not next anymore is dropped.
+func (b *Bot) prefetchNext() {
+	if b.state == BotStateWaitForTrack {
+		return
+	}
+
+	next := b.nextIdx()
+	if next >= len(b.tracks) {
+		b.prefetched = nil
+		return
+	}
+
+	track := b.tracks[next]
+	if b.prefetched != nil && b.prefetched.track == track {
+		return
+	}
+
+	p := &prefetchedTrack{
+		track: track,
+		done:  make(chan struct{}),
+	}
+	b.prefetched = p
+
+	go func() {
+		defer close(p.done)
+		p.title, p.streamURL, p.err = b.fetchTrack(track)
+	}()
+}
+
+// playStream streams the audio until the track ends, is skipped or the player is stopped.
+// Seeking restarts the stream at the requested position without leaving the track.
+// src is the source handed over from the previous track, a new source is opened if it is nil.
+func (b *Bot) playStream(stop chan struct{}, track *Track, title, streamURL string, src *pcmSource) (stopped bool, err error) {
+	var offset time.Duration
+
+	b.mu.Lock()
+	if b.resumeTrack == track {
+		offset = b.resumePosition
+	}
+	b.resumeTrack = nil
+	b.resumePosition = 0
+	b.mu.Unlock()
+
+	var recoveries, reconnects int
+stream:
+	for {
+		// the track may have been seeked before its stream started
+		if position, ok := b.takeSeek(track); ok {
+			offset = position
+		}
+
+		if src != nil && offset > 0 {
+			src.Close()
+			src = nil
+		}
+
+		if src == nil {
+			src, err = b.openSource(track, title, streamURL, offset)
+			if err != nil {
+				return false, err
+			}
+		}
+
+		done, err := b.startStream(stop, src)
+		if err != nil {
+			src.Close()
+			if err == errSkipped {
+				return false, nil
+			}
+			return err == errStopped, nil
+		}
+		src = nil
+
+		for {
+			select {
+			case err := <-done:
+				if err == dca.ErrVoiceConnClosed {
+					// the frames could not be sent, continue where the listeners last heard after rejoining
+					b.mu.RLock()
+					offset = b.position()
+					b.mu.RUnlock()
+
+					b.stop()
+
+					if reconnects == maxReconnects {
+						log.Printf("voice connection in guild %s keeps dropping", b.guidID)
+						return false, ErrVoiceDisconnected
+					}
+					reconnects++
+
+					stopped, err := b.reconnect(stop)
+					if err != nil || stopped {
+						return stopped, err
+					}
+					continue stream
+				}
+
+				b.handover = b.mixer.Handover()
+
+				b.mu.RLock()
+				position := b.position()
+				b.mu.RUnlock()
+
+				b.stop()
+				if err != nil && err != io.EOF {
+					return false, err
+				}
+
+				// the stream URL may have expired or been throttled, get a new one and continue where it ended
+				if b.handover == nil && endedEarly(track, position) && recoveries < maxStreamRecoveries {
+					recoveries++
+
+					_, streamURL, err = b.fetchTrack(track)
+					if err != nil {
+						log.Printf("cannot recover stream of %s: %v", track.ID, err)
+						return false, nil
+					}
+
+					offset = position
+					continue stream
+				}
+
+				return false, nil
+			case <-b.skipCh:
+				b.stop()
+				return false, nil
+			case <-b.seekCh:
+				position, ok := b.takeSeek(track)
+				if !ok {
+					// the request has been taken already or it was for another track
+					continue
+				}
+
+				b.stop()
+				offset = position
+				continue stream
+			case <-stop:
+				b.stop()
+				return true, nil
+			}
+		}
+	}
+}
+
+// takeSeek takes the pending seek request and returns its position if it is for the track.
+func (b *Bot) takeSeek(track *Track) (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	req := b.seeking
+	b.seeking = nil
+
+	if req == nil || req.track != track {
+		return 0, false
+	}
+
+	return req.position, true
+}
+
+// openSource starts decoding the track from the offset with the active audio filters.
+func (b *Bot) openSource(track *Track, title, streamURL string, offset time.Duration) (*pcmSource, error) {
+	b.mu.RLock()
+	filter, speed := b.audioFilter()
+	b.mu.RUnlock()
+
+	// a live stream always continues from the live edge
+	if track.Live {
+		offset = 0
+	}
+
+	return newPCMSource(track, title, streamURL, offset, filter, speed)
+}
+
+// openNextSource starts decoding the track after the playing one for crossfading.
+// It returns nil if the next track has not been resolved yet, so that the mixer never waits for YouTube.
+func (b *Bot) openNextSource() *pcmSource {
+	b.mu.RLock()
+	p := b.prefetched
+	next := b.nextIdx()
+	// the queue may have changed since the track was prefetched
+	stale := p == nil || next >= len(b.tracks) || p.track != b.tracks[next]
+	b.mu.RUnlock()
+
+	if stale {
+		return nil
+	}
+
+	select {
+	case <-p.done:
+	default:
+		return nil
+	}
+
+	if p.err != nil {
+		return nil
+	}
+
+	src, err := b.openSource(p.track, p.title, p.streamURL, 0)
+	if err != nil {
+		log.Println("cannot open next track: ", err)
+		return nil
+	}
+
+	return src
+}
+
+// takeHandover returns the handed over source if it belongs to the track, otherwise the source is closed.
+func (b *Bot) takeHandover(track *Track) *pcmSource {
+	src := b.handover
+	b.handover = nil
+
+	if src == nil {
+		return nil
+	}
+
+	if src.track != track {
+		src.Close()
+		return nil
+	}
+
+	return src
+}
+
+// startStream mixes the source, encodes it and sends it to the voice connection.
+// It returns errSkipped or errStopped instead if the track has been skipped or the player has been stopped.
+func (b *Bot) startStream(stop chan struct{}, src *pcmSource) (chan error, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if isClosed(stop) {
+		return nil, errStopped
+	}
+
+	if b.skipped {
+		return nil, errSkipped
+	}
+
+	// a wake up for a skip that has been handled already
+	select {
+	case <-b.skipCh:
+	default:
+	}
+
+	m := newMixer(src, b.crossfade, b.volume, b.openNextSource)
+
+	encoder, err := newOpusEncoder(m, dca.StdEncodeOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	done := make(chan error, 1)
+	b.mixer = m
+	b.encoder = encoder
+	b.streamSess = dca.NewStream(encoder, b.vc, done)
+	b.streamTrack = src.track
+	b.streamOffset = src.Position()
+	b.streamSpeed = src.speed
+
+	if b.state == BotStatePaused {
+		b.streamSess.SetPaused(true)
+	}
+
+	return done, nil
+}
+
+// audioFilter returns the ffmpeg filter chain of the active filters and its speed factor, b.mu must be held.
+func (b *Bot) audioFilter() (string, float64) {
+	filter, speed := audioFilterChain(b.filters)
+	if b.settings.Normalize {
+		if filter != "" {
+			filter += ","
+		}
+		filter += loudnormFilter
+	}
+
+	return filter, speed
+}
+
+// restartStream decodes the playing track again from the current position so that new audio filters take effect.
+func (b *Bot) restartStream() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BotStateWaitForTrack || b.currentTrackIdx >= len(b.tracks) {
+		return
+	}
+
+	b.requestSeek(b.position())
+}
+
+// position returns the playback position in the playing track, b.mu must be held.
+// It is zero until the stream of the playing track has started.
+func (b *Bot) position() time.Duration {
+	if b.streamSess == nil || b.currentTrackIdx >= len(b.tracks) || b.streamTrack != b.tracks[b.currentTrackIdx] {
+		return 0
+	}
+	// the offset is in the source time while the playback position is sped up or slowed down by the filters
+	return b.streamOffset + time.Duration(float64(b.streamSess.PlaybackPosition())*b.streamSpeed)
+}
+
+// Seek restarts the playing track at the position d.
+func (b *Bot) Seek(d time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.seek(d)
+}
+
+// SeekRelative moves the playback position of the playing track forward (or backward when delta is negative).
+func (b *Bot) SeekRelative(delta time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.seek(b.position() + delta)
+}
+
+// seek restarts the playing track at the position d, b.mu must be held.
+func (b *Bot) seek(d time.Duration) error {
+	if b.state == BotStateWaitForTrack || b.currentTrackIdx >= len(b.tracks) {
+		return ErrNotPlaying
+	}
+
+	track := b.tracks[b.currentTrackIdx]
+	if track.Live {
+		return ErrCannotSeekLive
+	}
+
+	if d < 0 {
+		d = 0
+	}
+
+	if track.Duration > 0 && d >= track.Duration {
+		return ErrSeekOutOfRange
+	}
+
+	b.requestSeek(d)
+
+	return nil
+}
+
+// requestSeek asks the play loop to restart the playing track at the position d, b.mu must be held.
+// It does not wait for the play loop since the play loop needs b.mu to restart the track.
+func (b *Bot) requestSeek(d time.Duration) {
+	b.seeking = &seekRequest{
+		track:    b.tracks[b.currentTrackIdx],
+		position: d,
+	}
+
+	select {
+	case b.seekCh <- struct{}{}:
+	default:
+	}
+}
+
+func (b *Bot) stop() {
+	b.streamSess.SetPaused(true)
+	b.encoder.Stop()
+	b.mixer.Close()
+}
+
+func (b *Bot) Pause() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != BotStatePlaying {
+		return errors.New("cannot pause: no music is playing")
+	}
+
+	b.state = BotStatePaused
+	// the stream is started paused if the track is still being resolved
+	if b.streamSess != nil {
+		b.streamSess.SetPaused(true)
+	}
+
+	return nil
+}
+
+func (b *Bot) Resume() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != BotStatePaused {
+		return errors.New("cannot resume: music is playing")
+	}
+
+	b.state = BotStatePlaying
+	if b.streamSess != nil {
+		b.streamSess.SetPaused(false)
+	}
+	return nil
+}
+
+func (b *Bot) Remove(idx int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if idx < 0 || idx >= len(b.tracks) {
+		return ErrTrackNotFound
+	}
+
+	if b.currentTrackIdx == idx {
+		return ErrCannotRemovePlayingTrack
+	}
+
+	b.tracks = append(b.tracks[:idx], b.tracks[idx+1:]...)
+
+	// keep pointing at the same track
+	if idx < b.currentTrackIdx {
+		b.currentTrackIdx--
+	}
+	b.prefetchNext()
+
+	return nil
+}
+
+// Move moves the track at index from to index to, shifting the tracks in between.
+func (b *Bot) Move(from, to int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if from < 0 || from >= len(b.tracks) || to < 0 || to >= len(b.tracks) {
+		return ErrTrackNotFound
+	}
+
+	if from == to {
+		return nil
+	}
+
+	track := b.tracks[from]
+	if from < to {
+		copy(b.tracks[from:to], b.tracks[from+1:to+1])
+	} else {
+		copy(b.tracks[to+1:from+1], b.tracks[to:from])
+	}
+	b.tracks[to] = track
+
+	// keep pointing at the same track
+	switch {
+	case from == b.currentTrackIdx:
+		b.currentTrackIdx = to
+	case from < b.currentTrackIdx && to >= b.currentTrackIdx:
+		b.currentTrackIdx--
+	case from > b.currentTrackIdx && to <= b.currentTrackIdx:
+		b.currentTrackIdx++
+	}
+	b.prefetchNext()
+
+	return nil
+}
+
+// VoteSkip records the user's vote to skip the playing track then skips it once enough listeners have voted.
+// listeners are the users in the voice channel, votes of users who left the channel are not counted.
+func (b *Bot) VoteSkip(userID string, listeners []string) (votes, required int, skipped bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BotStateWaitForTrack {
+		return 0, 0, false, ErrNotPlaying
+	}
+
+	required = int(math.Ceil(float64(len(listeners)) * b.settings.VoteSkipRatio))
+	if required < 1 {
+		required = 1
+	}
+
+	// the playing track is being skipped already, e.g. by a vote at the same time
+	if b.skipped {
+		return required, required, true, nil
+	}
+
+	if b.skipVotes == nil {
+		b.skipVotes = make(map[string]bool)
+	}
+	b.skipVotes[userID] = true
+
+	for _, id := range listeners {
+		if b.skipVotes[id] {
+			votes++
+		}
+	}
+
+	if votes < required {
+		return votes, required, false, nil
+	}
+
+	b.skipVotes = nil
+	_, _, err = b.goTo(b.currentTrackIdx + 1)
+	if err != nil {
+		return votes, required, false, err
+	}
+
+	return votes, required, true, nil
+}
+
+// Next skips n tracks, it returns the index of the track to be played which is the number of tracks at the end of the queue.
+// The play loop may not have moved on to the track yet.
+func (b *Bot) Next(n int) (int, error) {
+	b.mu.Lock()
+	idx, discover, err := b.goTo(b.playingIdx() + n)
+	b.mu.Unlock()
+
+	if discover {
+		return idx, b.discoverNextTrack()
+	}
+	return idx, err
+}
+
+func (b *Bot) Prev(n int) error {
+	b.mu.Lock()
+	_, discover, err := b.goTo(b.playingIdx() - n)
+	b.mu.Unlock()
+
+	if discover {
+		return b.discoverNextTrack()
+	}
+	return err
+}
+
+func (b *Bot) GoTo(idx int) error {
+	b.mu.Lock()
+	_, discover, err := b.goTo(idx)
+	b.mu.Unlock()
+
+	if discover {
+		return b.discoverNextTrack()
+	}
+	return err
+}
+
+// goTo plays the track at index idx, b.mu must be held.
+// It returns the index clamped to the queue, which is the number of tracks when going past the end,
+// and discover if the next track should be discovered, which is done after releasing b.mu.
+func (b *Bot) goTo(idx int) (target int, discover bool, err error) {
+	if len(b.tracks) == 0 {
+		return 0, false, ErrEmptyTracks
+	}
+
+	if idx < 0 {
+		idx = 0
+	}
+
+	if idx > len(b.tracks) {
+		idx = len(b.tracks)
+	}
+
+	if b.state == BotStateWaitForTrack {
+		b.currentTrackIdx = idx
+		if idx == len(b.tracks) {
+			return idx, b.autoDiscoverNextTrack, nil
+		}
+		b.startPlaying()
+		return idx, false, nil
+	}
+
+	// Skipping in playing state is done by:
+	// 1. Mark the playing track as skipped to the wanted track, the current track index stays at the playing track.
+	// 2. Wake up the play loop to stop the playing track, it moves on to the wanted track.
+	// The play loop cannot be waited for here since it needs b.mu to move on.
+	b.skipped = true
+	b.skipTo = nil
+	if idx < len(b.tracks) {
+		b.skipTo = b.tracks[idx]
+	}
+	b.prefetchNext()
+	select {
+	case b.skipCh <- struct{}{}:
+	default:
+	}
+
+	return idx, false, nil
+}
+
+// playingIdx returns the index of the playing track or the track to be played after a pending skip, b.mu must be held.
+// It is the length of the tracks when skipping past the last track.
+func (b *Bot) playingIdx() int {
+	if !b.skipped {
+		return b.currentTrackIdx
+	}
+
+	if b.skipTo == nil {
+		return len(b.tracks)
+	}
+
+	for i, track := range b.tracks {
+		if track == b.skipTo {
+			return i
+		}
+	}
+
+	// the track has been removed from the queue
+	return b.currentTrackIdx + 1
+}
+
+// Add appends the tracks to the queue, a *LimitError is returned if it exceeds the limits of the guild.
+func (b *Bot) Add(tracks ...*Track) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	err := b.checkLimits(tracks)
+	if err != nil {
+		return err
+	}
+
+	if b.settings.FairQueue {
+		b.fairAdd(tracks)
+	} else {
+		b.tracks = append(b.tracks, tracks...)
+	}
+	if b.originalTracks != nil {
+		b.originalTracks = append(b.originalTracks, tracks...)
+	}
+	if b.state == BotStateWaitForTrack {
+		b.startPlaying()
+	} else {
+		b.prefetchNext()
+	}
+
+	return nil
+}
+
+// fairQueue interleaves the upcoming tracks round-robin by requester, b.mu must be held.
+func (b *Bot) fairQueue() {
+	start := b.upcomingIdx()
+	if start >= len(b.tracks) {
+		return
 	}
 
-	close(b.skipCh)
-	close(b.stopCh)
+	fair := fairOrder(nil, b.tracks[start:], b.playingTrack())
+	copy(b.tracks[start:], fair)
+}
 
-	if b.vc != nil {
-		b.vc.Disconnect()
-		b.vc.Close()
+// fairAdd adds the tracks to the upcoming tracks in their round-robin turns, b.mu must be held.
+// The upcoming tracks keep their order so that tracks placed by hand (e.g. moved) stay where they are.
+func (b *Bot) fairAdd(tracks []*Track) {
+	start := b.upcomingIdx()
+	if start > len(b.tracks) {
+		start = len(b.tracks)
 	}
 
-	if b.errCh != nil {
-		close(b.errCh)
-	}
+	fair := fairOrder(b.tracks[start:], tracks, b.playingTrack())
+	b.tracks = append(b.tracks[:start:start], fair...)
 }
 
-func (b *Bot) play() {
-	if b.vc == nil {
-		b.sendError(ErrNotInVoiceChannel)
-		return
+// playingTrack returns the playing track or nil, b.mu must be held.
+func (b *Bot) playingTrack() *Track {
+	if b.state == BotStateWaitForTrack || b.currentTrackIdx >= len(b.tracks) {
+		return nil
 	}
+	return b.tracks[b.currentTrackIdx]
+}
 
-	b.mu.Lock()
-	b.state = BotStatePlaying
-	b.mu.Unlock()
-	b.vc.Speaking(true)
-	defer func() {
-		b.mu.Lock()
-		b.state = BotStateWaitForTrack
-		b.mu.Unlock()
-		b.vc.Speaking(false)
-	}()
+// fairOrder places the tracks among the upcoming tracks round-robin by requester and returns the new upcoming tracks.
+// The n-th track of a requester goes after the last track that is the n-th or earlier track of its requester,
+// so the tracks of each requester keep their order and the upcoming tracks are not reordered.
+// The playing track counts as the first track of its requester so that they go last.
+func fairOrder(upcoming, tracks []*Track, playing *Track) []*Track {
+	turns := make(map[string]int)
+	if playing != nil {
+		turns[playing.RequesterID] = 1
+	}
 
-	for b.currentTrackIdx < len(b.tracks) {
-		var err error
+	order := make([]*Track, 0, len(upcoming)+len(tracks))
+	orderTurns := make([]int, 0, cap(order))
+	for _, track := range upcoming {
+		turns[track.RequesterID]++
+		order = append(order, track)
+		orderTurns = append(orderTurns, turns[track.RequesterID])
+	}
 
-		video, err := b.ytClient.GetVideo(b.tracks[b.currentTrackIdx].ID)
-		if err != nil {
-			b.sendError(err)
-		}
+	for _, track := range tracks {
+		turns[track.RequesterID]++
+		turn := turns[track.RequesterID]
 
-		streamURL, err := b.ytClient.GetAudioStreamURL(video)
-		if err != nil {
-			b.sendError(err)
+		idx := 0
+		for i, t := range orderTurns {
+			if t <= turn {
+				idx = i + 1
+			}
 		}
 
-		_, sendErr := b.dg.ChannelMessageSend(b.textChannelID, fmt.Sprintf("Playing `%s`", video.Title))
-		if sendErr != nil {
-			log.Println(sendErr)
-		}
+		order = append(order, nil)
+		copy(order[idx+1:], order[idx:])
+		order[idx] = track
 
-		var skipped chan struct{}
-		if err != nil {
-			b.dg.ChannelMessageSend(b.textChannelID, fmt.Sprintf("Cannot play `%s`, skipping...", video.Title))
-		} else {
-			dca.Logger = log.New(ioutil.Discard, "", 0)
-			b.encodeSess, err = dca.EncodeFile(streamURL, dca.StdEncodeOptions)
-			if err != nil {
-				b.sendError(err)
-				return
-			}
+		orderTurns = append(orderTurns, 0)
+		copy(orderTurns[idx+1:], orderTurns[idx:])
+		orderTurns[idx] = turn
+	}
 
-			done := make(chan error)
-			b.streamSess = dca.NewStream(b.encodeSess, b.vc, done)
+	return order
+}
 
-			select {
-			case err := <-done:
-				b.stop()
-				if err != nil && err != io.EOF {
-					b.sendError(err)
-					return
-				}
-			case skipped = <-b.skipCh:
-				b.stop()
-			case <-b.stopCh:
-				b.stop()
-				return
-			}
-		}
+// Insert inserts the tracks at index idx, the tracks from idx onwards are shifted back.
+// It returns the index where the tracks are inserted, which is moved to the track to be played next
+// if the player is waiting for tracks so that the played tracks are not played again.
+func (b *Bot) Insert(idx int, tracks ...*Track) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
 
-		if b.currentTrackIdx == len(b.tracks)-1 && b.autoDiscoverNextTrack {
-			err = b.discoverNextTrack()
-			if err != nil {
-				log.Println("cannot discover next track: ", err)
-			}
-		}
+	if idx < 0 || idx > len(b.tracks) {
+		return 0, ErrTrackNotFound
+	}
 
-		b.mu.Lock()
-		b.currentTrackIdx++
-		b.mu.Unlock()
+	err := b.checkLimits(tracks)
+	if err != nil {
+		return 0, err
+	}
 
-		if skipped != nil {
-			skipped <- struct{}{}
-		}
+	if b.state == BotStateWaitForTrack && idx < b.currentTrackIdx {
+		idx = b.currentTrackIdx
 	}
-}
 
-func (b *Bot) stop() {
-	b.streamSess.SetPaused(true)
-	b.encodeSess.Stop()
+	b.insert(idx, tracks)
+
+	return idx, nil
 }
 
-func (b *Bot) Pause() error {
+// InsertNext inserts the tracks to be played right after the current playing track.
+func (b *Bot) InsertNext(tracks ...*Track) error {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	if b.state != BotStatePlaying {
-		return errors.New("cannot pause: no music is playing")
+	err := b.checkLimits(tracks)
+	if err != nil {
+		return err
 	}
 
-	b.state = BotStatePaused
-	b.streamSess.SetPaused(true)
+	b.insert(b.upcomingIdx(), tracks)
 
 	return nil
 }
 
-func (b *Bot) Resume() error {
-	b.mu.Lock()
-	defer b.mu.Unlock()
+func (b *Bot) insert(idx int, tracks []*Track) {
+	// keep pointing at the same track
+	if idx < b.upcomingIdx() {
+		b.currentTrackIdx += len(tracks)
+	}
 
-	if b.state != BotStatePaused {
-		return errors.New("cannot resume: music is playing")
+	b.tracks = append(b.tracks, tracks...)
+	copy(b.tracks[idx+len(tracks):], b.tracks[idx:])
+	copy(b.tracks[idx:], tracks)
+
+	if b.originalTracks != nil {
+		b.originalTracks = append(b.originalTracks, tracks...)
 	}
+	if b.state == BotStateWaitForTrack {
+		b.startPlaying()
+	} else {
+		b.prefetchNext()
+	}
+}
 
+// startPlaying starts the play loop, b.mu must be held.
+// The state is set before the loop starts so that adding tracks in quick succession does not start another loop.
+func (b *Bot) startPlaying() {
 	b.state = BotStatePlaying
-	b.streamSess.SetPaused(false)
-	return nil
+	b.stopCh = make(chan struct{})
+	b.loopDone = make(chan struct{})
+	go b.play(b.stopCh, b.loopDone)
 }
 
-func (b *Bot) Remove(idx int) error {
+// upcomingIdx returns the index of the first track that has not been played yet.
+func (b *Bot) upcomingIdx() int {
+	if b.state == BotStateWaitForTrack {
+		return b.currentTrackIdx
+	}
+	return b.currentTrackIdx + 1
+}
+
+// Shuffle randomizes the upcoming tracks while keeping the playing track in place.
+// The order before the first shuffle is remembered and can be restored with Unshuffle.
+func (b *Bot) Shuffle() error {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	if idx < 0 || idx >= len(b.tracks) {
-		return ErrTrackNotFound
+	start := b.upcomingIdx()
+	if start >= len(b.tracks) {
+		return ErrNoUpcomingTracks
 	}
 
-	if b.currentTrackIdx == idx {
-		return ErrCannotRemovePlayingTrack
+	if b.originalTracks == nil {
+		b.originalTracks = make([]*Track, len(b.tracks))
+		copy(b.originalTracks, b.tracks)
 	}
 
-	b.tracks = append(b.tracks[:idx], b.tracks[idx+1:]...)
+	upcoming := b.tracks[start:]
+	rand.Shuffle(len(upcoming), func(i, j int) {
+		upcoming[i], upcoming[j] = upcoming[j], upcoming[i]
+	})
+	b.prefetchNext()
 
 	return nil
 }
 
-func (b *Bot) Next(n int) error {
-	return b.GoTo(b.currentTrackIdx + n)
-}
-
-func (b *Bot) Prev(n int) error {
-	return b.GoTo(b.currentTrackIdx - n)
-}
-
-func (b *Bot) GoTo(idx int) error {
+// Unshuffle restores the upcoming tracks to the order before shuffling.
+// Tracks added while shuffled are placed in the order they were added.
+func (b *Bot) Unshuffle() error {
 	b.mu.Lock()
+	defer b.mu.Unlock()
 
-	if len(b.tracks) == 0 {
-		return ErrEmptyTracks
+	if b.originalTracks == nil {
+		return ErrNotShuffled
 	}
 
-	if idx < 0 {
-		idx = 0
+	start := b.upcomingIdx()
+	if start > len(b.tracks) {
+		start = len(b.tracks)
 	}
 
-	if idx > len(b.tracks) {
-		idx = len(b.tracks)
+	upcoming := make(map[*Track]bool)
+	for _, track := range b.tracks[start:] {
+		upcoming[track] = true
 	}
 
-	b.currentTrackIdx = idx
-
-	if b.state == BotStateWaitForTrack {
-		if idx == len(b.tracks) {
-			if b.autoDiscoverNextTrack {
-				return b.discoverNextTrack()
-			}
-		} else {
-			go b.play()
+	restored := make([]*Track, 0, len(upcoming))
+	for _, track := range b.originalTracks {
+		if upcoming[track] {
+			restored = append(restored, track)
+			delete(upcoming, track)
 		}
-		return nil
 	}
 
-	// Skipping in playing state is done by:
-	// 1. Set the current track index to the one before the wanted track.
-	// 2. Send skip signal to termninate the current playing track then wait the playloop to iterate to the next track.
-	b.currentTrackIdx--
-	done := make(chan struct{})
-	b.skipCh <- done
-
-	b.mu.Unlock()
+	// keep tracks that are not in the original order (e.g. moved from the played tracks) at the end
+	for _, track := range b.tracks[start:] {
+		if upcoming[track] {
+			restored = append(restored, track)
+		}
+	}
 
-	<-done
-	close(done)
+	copy(b.tracks[start:], restored)
+	b.originalTracks = nil
+	b.prefetchNext()
 
 	return nil
 }
 
-func (b *Bot) Add(track *Track) {
-	b.mu.Lock()
-	defer b.mu.Unlock()
-
-	b.tracks = append(b.tracks, track)
-	if b.state == BotStateWaitForTrack {
-		go b.play()
-	}
+func (b *Bot) Shuffled() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.originalTracks != nil
 }
 
 // Clear clears the upcomming tracks (tracks queue) then return a total removed tracks
@@ -334,22 +1533,26 @@ func (b *Bot) Clear(all bool) int {
 	}
 
 	b.currentTrackIdx = 0
+	b.originalTracks = nil
+	b.prefetchNext()
 
 	return total
 }
 
 func (b *Bot) Reset() {
+	b.stopPlaying()
+
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	if b.state != BotStateWaitForTrack {
-		b.stopCh <- struct{}{}
-	}
-
+	b.skipped = false
+	b.skipTo = nil
 	b.currentTrackIdx = 0
 	b.state = BotStateWaitForTrack
 	b.tracks = nil
+	b.originalTracks = nil
 	b.autoDiscoverNextTrack = false
+	b.loopMode = LoopModeOff
 }
 
 func (b *Bot) VoiceChannelID() string {
@@ -358,6 +1561,18 @@ func (b *Bot) VoiceChannelID() string {
 	return b.voiceChannelID
 }
 
+func (b *Bot) LastInvokerID() string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.lastInvokerID
+}
+
+func (b *Bot) SetLastInvokerID(userID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastInvokerID = userID
+}
+
 func (b *Bot) TextChannelID() string {
 	b.mu.RLock()
 	defer b.mu.RUnlock()
@@ -382,6 +1597,59 @@ func (b *Bot) CurrentTrackIndex() int {
 	return b.currentTrackIdx
 }
 
+// CurrentTrack returns the playing track, ok is false when no track is playing.
+func (b *Bot) CurrentTrack() (track Track, ok bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.state == BotStateWaitForTrack || b.currentTrackIdx >= len(b.tracks) {
+		return Track{}, false
+	}
+
+	return *b.tracks[b.currentTrackIdx], true
+}
+
+// Position returns the elapsed playback time of the playing track, paused time is not counted.
+func (b *Bot) Position() time.Duration {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.state == BotStateWaitForTrack {
+		return 0
+	}
+
+	return b.position()
+}
+
+// TrackAt returns the track at index idx.
+func (b *Bot) TrackAt(idx int) (Track, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if idx < 0 || idx >= len(b.tracks) {
+		return Track{}, false
+	}
+
+	return *b.tracks[idx], true
+}
+
+// Tracks returns a copy of all tracks.
+func (b *Bot) Tracks() []*Track {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.copyTracks()
+}
+
+// copyTracks copies the tracks so that they can be used without holding b.mu.
+func (b *Bot) copyTracks() []*Track {
+	tracks := make([]*Track, len(b.tracks))
+	for i, track := range b.tracks {
+		t := *track
+		tracks[i] = &t
+	}
+	return tracks
+}
+
 func (b *Bot) TotalTracks() int {
 	b.mu.RLock()
 	defer b.mu.RUnlock()
@@ -394,8 +1662,14 @@ func (b *Bot) sendError(err error) {
 	}
 }
 
+type TrackInfo struct {
+	No      int
+	Track   Track
+	Playing bool
+}
+
 type TrackPage struct {
-	TrackInfos  []string
+	TrackInfos  []TrackInfo
 	Page        int
 	PageSize    int
 	TotalPage   int
@@ -411,7 +1685,16 @@ func (tp TrackPage) DisplayText() string {
 	s := fmt.Sprintf("Total tracks: %d (%d queued)\n```", tp.TotalTracks, tp.TotalQueued)
 
 	for _, info := range tp.TrackInfos {
-		s += info + "\n"
+		duration := info.Track.Duration.String()
+		if info.Track.Live {
+			duration = "LIVE"
+		}
+
+		s += fmt.Sprintf("%d %s  %s  (%s)", info.No, info.Track.Name, duration, info.Track.RequestInfo())
+		if info.Playing {
+			s += "  [Playing]"
+		}
+		s += "\n"
 	}
 
 	s += fmt.Sprintf("```Page %d of %d", tp.Page, tp.TotalPage)
@@ -438,13 +1721,13 @@ func (b *Bot) List(page, pageSize int) TrackPage {
 		end = len(b.tracks)
 	}
 
-	var infos []string
+	var infos []TrackInfo
 	for i := start; i < end; i++ {
-		info := fmt.Sprintf("%d %s  %s", i+1, b.tracks[i].Name, b.tracks[i].Duration)
-		if i == b.currentTrackIdx {
-			info += "  [Playing]"
-		}
-		infos = append(infos, info)
+		infos = append(infos, TrackInfo{
+			No:      i + 1,
+			Track:   *b.tracks[i],
+			Playing: i == b.currentTrackIdx,
+		})
 	}
 
 	trackPage := TrackPage{
@@ -477,19 +1760,163 @@ func (b *Bot) SetAutoDiscoverNextTrack(v bool) {
 	b.autoDiscoverNextTrack = v
 }
 
+func (b *Bot) Volume() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.volume
+}
+
+// SetVolume sets the volume in percent of the original volume (0-200).
+// The volume is applied by the mixer so that it takes effect on the playing track.
+func (b *Bot) SetVolume(v int) error {
+	if v < 0 || v > MaxVolume {
+		return ErrInvalidVolume
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.volume = v
+	if b.mixer != nil {
+		b.mixer.SetVolume(v)
+	}
+
+	return nil
+}
+
+func (b *Bot) Crossfade() time.Duration {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.crossfade
+}
+
+// SetCrossfade sets the duration of mixing the end of a track with the beginning of the next track, zero disables crossfading.
+// It takes effect from the next track.
+func (b *Bot) SetCrossfade(d time.Duration) error {
+	if d < 0 || d > MaxCrossfade {
+		return ErrInvalidCrossfade
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.crossfade = d
+
+	return nil
+}
+
+func (b *Bot) Settings() Settings {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.settings
+}
+
+// SetSettings applies the guild settings, the playing track is re-encoded if the encode options are changed.
+func (b *Bot) SetSettings(settings Settings) {
+	b.mu.Lock()
+	restart := settings.Normalize != b.settings.Normalize
+	fair := settings.FairQueue && !b.settings.FairQueue
+	b.settings = settings
+	if fair {
+		b.fairQueue()
+		b.prefetchNext()
+	}
+	b.mu.Unlock()
+
+	if restart {
+		b.restartStream()
+	}
+}
+
+// Filters returns the names of the active audio filters.
+func (b *Bot) Filters() []string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return append([]string(nil), b.filters...)
+}
+
+// ToggleFilter enables the audio filter if it is disabled or disables it otherwise, then returns whether it is enabled.
+// The playing track is re-encoded from the current position to apply the filters immediately.
+func (b *Bot) ToggleFilter(name string) (bool, error) {
+	if _, ok := findAudioFilter(name); !ok {
+		return false, ErrFilterNotFound
+	}
+
+	b.mu.Lock()
+	enabled := true
+	for i, f := range b.filters {
+		if f == name {
+			b.filters = append(b.filters[:i:i], b.filters[i+1:]...)
+			enabled = false
+			break
+		}
+	}
+	if enabled {
+		b.filters = append(b.filters, name)
+	}
+	b.mu.Unlock()
+
+	b.restartStream()
+
+	return enabled, nil
+}
+
+// ClearFilters disables all audio filters.
+func (b *Bot) ClearFilters() {
+	b.mu.Lock()
+	b.filters = nil
+	b.mu.Unlock()
+
+	b.restartStream()
+}
+
+func (b *Bot) LoopMode() LoopMode {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.loopMode
+}
+
+func (b *Bot) SetLoopMode(mode LoopMode) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.loopMode = mode
+	b.prefetchNext()
+}
+
+// discoverNextTrack adds a track suggested from the last track to the queue.
+// b.mu must not be held since it waits for YouTube.
 func (b *Bot) discoverNextTrack() error {
-	b.dg.ChannelMessageSend(b.textChannelID, "Discovering next music...")
+	b.mu.RLock()
+	textChannelID := b.textChannelID
+	maxDuration := b.settings.MaxTrackDuration
+	var lastTrackID string
+	if len(b.tracks) > 0 {
+		lastTrackID = b.tracks[len(b.tracks)-1].ID
+	}
+	b.mu.RUnlock()
 
-	lastTrackIdx := len(b.tracks) - 1
-	if lastTrackIdx < 0 {
+	if lastTrackID == "" {
 		return ErrEmptyTracks
 	}
 
-	videos, err := b.ytClient.GetSuggestedVideos(b.tracks[lastTrackIdx].ID)
+	b.dg.ChannelMessageSend(textChannelID, "Discovering next music...")
+
+	videos, err := b.ytClient.GetSuggestedVideos(lastTrackID)
 	if err != nil {
 		return err
 	}
 
+	if maxDuration > 0 {
+		var allowed []youtube.VideoInfo
+		for _, video := range videos {
+			if !video.Live && video.Duration <= maxDuration {
+				allowed = append(allowed, video)
+			}
+		}
+		videos = allowed
+	}
+
 	if len(videos) == 0 {
 		return errors.New("no tracks discovered")
 	}
@@ -498,14 +1925,15 @@ func (b *Bot) discoverNextTrack() error {
 	video := videos[expRandInt(len(videos))]
 
 	track := &Track{
-		ID:       video.ID,
-		Name:     video.Title,
-		Duration: video.Duration,
+		ID:          video.ID,
+		Name:        video.Title,
+		Duration:    video.Duration,
+		Live:        video.Live,
+		RequestedAt: time.Now(),
+		Source:      TrackSourceAutoplay,
 	}
 
-	go b.Add(track)
-
-	return nil
+	return b.Add(track)
 }
 
 func expRandInt(n int) int {
@@ -0,0 +1,241 @@
+package pammy
+
+import (
+	"reflect"
+	"testing"
+)
+
+// testTracks returns tracks with the IDs, the requester of a track is the first letter of its ID.
+func testTracks(ids ...string) []*Track {
+	tracks := make([]*Track, len(ids))
+	for i, id := range ids {
+		tracks[i] = &Track{ID: id, RequesterID: id[:1]}
+	}
+	return tracks
+}
+
+func trackIDs(tracks []*Track) []string {
+	ids := make([]string, len(tracks))
+	for i, track := range tracks {
+		ids[i] = track.ID
+	}
+	return ids
+}
+
+func TestFairOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		upcoming []string
+		tracks   []string
+		playing  string
+		want     []string
+	}{
+		{name: "empty queue", tracks: []string{"a1", "a2"}, want: []string{"a1", "a2"}},
+		{name: "interleave new requester", upcoming: []string{"a1", "a2", "a3"}, tracks: []string{"b1", "b2"}, want: []string{"a1", "b1", "a2", "b2", "a3"}},
+		{name: "playing requester goes last", upcoming: []string{"a1"}, tracks: []string{"b1"}, playing: "a0", want: []string{"b1", "a1"}},
+		{name: "keep manual placement", upcoming: []string{"b1", "a1", "a2"}, tracks: []string{"c1"}, want: []string{"b1", "a1", "c1", "a2"}},
+		{name: "keep order of requester", upcoming: []string{"a1", "b1"}, tracks: []string{"a2", "a3"}, want: []string{"a1", "b1", "a2", "a3"}},
+		{name: "reorder all", tracks: []string{"a1", "a2", "b1", "b2"}, playing: "a0", want: []string{"b1", "a1", "b2", "a2"}},
+	}
+
+	for _, tt := range tests {
+		var playing *Track
+		if tt.playing != "" {
+			playing = testTracks(tt.playing)[0]
+		}
+
+		got := trackIDs(fairOrder(testTracks(tt.upcoming...), testTracks(tt.tracks...), playing))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// The bots below wait for tracks so that changing the queue does not prefetch tracks from YouTube.
+
+func TestMove(t *testing.T) {
+	tests := []struct {
+		name       string
+		from, to   int
+		want       []string
+		wantCursor int
+		wantErr    error
+	}{
+		{name: "move current track", from: 2, to: 0, want: []string{"t2", "t0", "t1", "t3", "t4"}, wantCursor: 0},
+		{name: "move before current after it", from: 0, to: 3, want: []string{"t1", "t2", "t3", "t0", "t4"}, wantCursor: 1},
+		{name: "move after current before it", from: 4, to: 1, want: []string{"t0", "t4", "t1", "t2", "t3"}, wantCursor: 3},
+		{name: "move after current", from: 3, to: 4, want: []string{"t0", "t1", "t2", "t4", "t3"}, wantCursor: 2},
+		{name: "same position", from: 1, to: 1, want: []string{"t0", "t1", "t2", "t3", "t4"}, wantCursor: 2},
+		{name: "out of range", from: 5, to: 0, want: []string{"t0", "t1", "t2", "t3", "t4"}, wantCursor: 2, wantErr: ErrTrackNotFound},
+	}
+
+	for _, tt := range tests {
+		b := &Bot{tracks: testTracks("t0", "t1", "t2", "t3", "t4"), currentTrackIdx: 2}
+
+		err := b.Move(tt.from, tt.to)
+		if err != tt.wantErr {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+
+		if got := trackIDs(b.tracks); !reflect.DeepEqual(got, tt.want) || b.currentTrackIdx != tt.wantCursor {
+			t.Errorf("%s: got %v at %d, want %v at %d", tt.name, got, b.currentTrackIdx, tt.want, tt.wantCursor)
+		}
+	}
+}
+
+func TestRemove(t *testing.T) {
+	tests := []struct {
+		name       string
+		idx        int
+		want       []string
+		wantCursor int
+		wantErr    error
+	}{
+		{name: "before current", idx: 0, want: []string{"t1", "t2"}, wantCursor: 0},
+		{name: "after current", idx: 2, want: []string{"t0", "t1"}, wantCursor: 1},
+		{name: "current", idx: 1, want: []string{"t0", "t1", "t2"}, wantCursor: 1, wantErr: ErrCannotRemovePlayingTrack},
+		{name: "out of range", idx: 3, want: []string{"t0", "t1", "t2"}, wantCursor: 1, wantErr: ErrTrackNotFound},
+	}
+
+	for _, tt := range tests {
+		b := &Bot{tracks: testTracks("t0", "t1", "t2"), currentTrackIdx: 1}
+
+		err := b.Remove(tt.idx)
+		if err != tt.wantErr {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+
+		if got := trackIDs(b.tracks); !reflect.DeepEqual(got, tt.want) || b.currentTrackIdx != tt.wantCursor {
+			t.Errorf("%s: got %v at %d, want %v at %d", tt.name, got, b.currentTrackIdx, tt.want, tt.wantCursor)
+		}
+	}
+}
+
+func TestInsert(t *testing.T) {
+	tests := []struct {
+		name       string
+		cursor     int
+		idx        int
+		want       []string
+		wantIdx    int
+		wantCursor int
+		wantErr    error
+	}{
+		{name: "at cursor", cursor: 1, idx: 1, want: []string{"t0", "n0", "n1", "t1", "t2"}, wantIdx: 1, wantCursor: 1},
+		{name: "after cursor", cursor: 1, idx: 2, want: []string{"t0", "t1", "n0", "n1", "t2"}, wantIdx: 2, wantCursor: 1},
+		{name: "before cursor", cursor: 2, idx: 0, want: []string{"t0", "t1", "n0", "n1", "t2"}, wantIdx: 2, wantCursor: 2},
+		{name: "after finished queue", cursor: 3, idx: 0, want: []string{"t0", "t1", "t2", "n0", "n1"}, wantIdx: 3, wantCursor: 3},
+		{name: "out of range", cursor: 1, idx: 4, want: []string{"t0", "t1", "t2"}, wantCursor: 1, wantErr: ErrTrackNotFound},
+	}
+
+	for _, tt := range tests {
+		// the play loop started by inserting returns right away without a voice connection
+		b := &Bot{tracks: testTracks("t0", "t1", "t2"), currentTrackIdx: tt.cursor}
+
+		idx, err := b.Insert(tt.idx, testTracks("n0", "n1")...)
+		if err != tt.wantErr || idx != tt.wantIdx {
+			t.Errorf("%s: got %d, %v, want %d, %v", tt.name, idx, err, tt.wantIdx, tt.wantErr)
+		}
+
+		got := trackIDs(b.Tracks())
+		if cursor := b.CurrentTrackIndex(); !reflect.DeepEqual(got, tt.want) || cursor != tt.wantCursor {
+			t.Errorf("%s: got %v at %d, want %v at %d", tt.name, got, cursor, tt.want, tt.wantCursor)
+		}
+	}
+}
+
+func TestUnshuffle(t *testing.T) {
+	original := testTracks("t0", "t1", "t2", "t3")
+	added := testTracks("n0")[0]
+
+	tests := []struct {
+		name     string
+		tracks   []*Track
+		original []*Track
+		want     []string
+		wantErr  error
+	}{
+		{
+			name:     "restore upcoming",
+			tracks:   []*Track{original[0], original[3], original[1], original[2]},
+			original: original,
+			want:     []string{"t0", "t1", "t2", "t3"},
+		},
+		{
+			name:     "keep played tracks",
+			tracks:   []*Track{original[2], original[3], original[1], original[0]},
+			original: original,
+			want:     []string{"t2", "t0", "t1", "t3"},
+		},
+		{
+			name:     "tracks not in original order go last",
+			tracks:   []*Track{original[0], added, original[2], original[1]},
+			original: original[:3],
+			want:     []string{"t0", "t1", "t2", "n0"},
+		},
+		{
+			name:    "not shuffled",
+			tracks:  []*Track{original[0], original[2], original[1]},
+			want:    []string{"t0", "t2", "t1"},
+			wantErr: ErrNotShuffled,
+		},
+	}
+
+	for _, tt := range tests {
+		b := &Bot{tracks: tt.tracks, originalTracks: tt.original, currentTrackIdx: 1}
+
+		err := b.Unshuffle()
+		if err != tt.wantErr {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+
+		if got := trackIDs(b.tracks); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+
+		if b.originalTracks != nil {
+			t.Errorf("%s: original order is kept after unshuffling", tt.name)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		name    string
+		state   BotState
+		cursor  int
+		skipTo  int
+		n       int
+		want    int
+		wantErr error
+	}{
+		// the play loop started by skipping returns right away without a voice connection
+		{name: "waiting", state: BotStateWaitForTrack, cursor: 0, skipTo: -1, n: 1, want: 1},
+		{name: "waiting after finished queue", state: BotStateWaitForTrack, cursor: 3, skipTo: -1, n: 1, want: 3},
+		{name: "playing", state: BotStatePlaying, cursor: 1, skipTo: -1, n: 1, want: 2},
+		{name: "playing past the end", state: BotStatePlaying, cursor: 0, skipTo: -1, n: 5, want: 3},
+		{name: "pending skip", state: BotStatePlaying, cursor: 0, skipTo: 1, n: 1, want: 2},
+	}
+
+	for _, tt := range tests {
+		b := &Bot{tracks: testTracks("t0", "t1", "t2"), currentTrackIdx: tt.cursor, state: tt.state}
+		if tt.skipTo >= 0 {
+			b.skipped = true
+			b.skipTo = b.tracks[tt.skipTo]
+		}
+		// the track skipped to has been prefetched so that skipping does not prefetch it from YouTube
+		if tt.state == BotStatePlaying && tt.want < len(b.tracks) {
+			b.prefetched = &prefetchedTrack{track: b.tracks[tt.want]}
+		}
+
+		got, err := b.Next(tt.n)
+		if err != tt.wantErr || got != tt.want {
+			t.Errorf("%s: got %d, %v, want %d, %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	b := &Bot{}
+	if _, err := b.Next(1); err != ErrEmptyTracks {
+		t.Errorf("empty queue: error = %v, want %v", err, ErrEmptyTracks)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/noppawitt/pammy/youtube"
@@ -15,9 +16,21 @@ type Client struct {
 	commandHandlers map[string]CommandHandleFunc
 	ytClient        *youtube.Client
 	hub             *Hub
+	store           *Store
+	policy          *Policy
+	cfg             Config
 }
 
-func NewClient(token string) (*Client, error) {
+type Config struct {
+	// DataDir is the directory to persist the bot states, the states are not persisted when empty.
+	DataDir string
+	// Resume rejoins the voice channels and continues playing after restart.
+	Resume bool
+	// AutoLeave is when the bots leave their voice channels by themselves.
+	AutoLeave AutoLeave
+}
+
+func NewClient(token string, cfg Config) (*Client, error) {
 	dg, err := discordgo.New("Bot " + token)
 	if err != nil {
 		return nil, err
@@ -25,11 +38,24 @@ func NewClient(token string) (*Client, error) {
 
 	ytClient := youtube.NewClient()
 
+	var store *Store
+	if cfg.DataDir != "" {
+		store, err = NewStore(cfg.DataDir)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	hub := NewHub(dg, ytClient, store, cfg.AutoLeave)
+
 	b := &Client{
 		dg:              dg,
 		commandHandlers: make(map[string]CommandHandleFunc),
 		ytClient:        ytClient,
-		hub:             NewHub(dg, ytClient),
+		hub:             hub,
+		store:           store,
+		policy:          NewPolicy(hub),
+		cfg:             cfg,
 	}
 
 	return b, nil
@@ -37,6 +63,7 @@ func NewClient(token string) (*Client, error) {
 
 func (c *Client) Start() error {
 	c.dg.AddHandler(c.handleInteractiveCreate)
+	c.dg.AddHandler(c.handleVoiceStateUpdate)
 
 	c.dg.Identify.Intents = discordgo.IntentsGuilds | discordgo.IntentsGuildMessages | discordgo.IntentsGuildVoiceStates
 
@@ -49,14 +76,36 @@ func (c *Client) Start() error {
 
 	c.AddGlobalSlashCommand(NewAddCommand(c.hub, c.ytClient))
 	c.AddGlobalSlashCommand(NewNextCommand(c.hub))
+	c.AddGlobalSlashCommand(NewVoteSkipCommand(c.hub))
 	c.AddGlobalSlashCommand(NewPauseCommand(c.hub))
 	c.AddGlobalSlashCommand(NewResumeCommand(c.hub))
 	c.AddGlobalSlashCommand(NewListCommand(c.hub))
 	c.AddGlobalSlashCommand(NewRemoveCommand(c.hub))
+	c.AddGlobalSlashCommand(NewMoveCommand(c.hub))
 	c.AddGlobalSlashCommand(NewClearCommand(c.hub))
 	c.AddGlobalSlashCommand(NewResetCommand(c.hub))
 	c.AddGlobalSlashCommand(NewLeaveCommand(c.hub))
 	c.AddGlobalSlashCommand(NewAutoPlayCommand(c.hub))
+	c.AddGlobalSlashCommand(NewFairQueueCommand(c.hub))
+	c.AddGlobalSlashCommand(NewShuffleCommand(c.hub))
+	c.AddGlobalSlashCommand(NewUnshuffleCommand(c.hub))
+	c.AddGlobalSlashCommand(NewLoopCommand(c.hub))
+	c.AddGlobalSlashCommand(NewSeekCommand(c.hub))
+	c.AddGlobalSlashCommand(NewNowPlayingCommand(c.hub))
+	c.AddGlobalSlashCommand(NewVolumeCommand(c.hub))
+	c.AddGlobalSlashCommand(NewFilterCommand(c.hub))
+	c.AddGlobalSlashCommand(NewCrossfadeCommand(c.hub))
+	c.AddGlobalSlashCommand(NewSettingsCommand(c.hub))
+	if c.store != nil {
+		c.AddGlobalSlashCommand(NewPlaylistCommand(c.hub, c.store))
+	}
+
+	err = c.hub.Restore(c.cfg.Resume)
+	if err != nil {
+		log.Println("cannot restore bot states: ", err)
+	}
+	go c.hub.Autosave(time.Minute)
+	go c.hub.Watch(10 * time.Second)
 
 	log.Println("Pammy is now running.")
 
@@ -66,6 +115,8 @@ func (c *Client) Start() error {
 
 	log.Println("Pammy is shutting down.")
 
+	c.hub.Close()
+
 	return nil
 }
 
@@ -83,7 +134,22 @@ func (c *Client) handleInteractiveCreate(s *discordgo.Session, i *discordgo.Inte
 		return
 	}
 
+	if !c.policy.Allowed(i) {
+		respondTextPrivate(s, i.Interaction, "You are not allowed to use this command")
+		return
+	}
+
 	h(s, i)
+
+	if bot, ok := c.hub.GetBot(i.GuildID); ok {
+		bot.SetLastInvokerID(i.Member.User.ID)
+	}
+}
+
+func (c *Client) handleVoiceStateUpdate(s *discordgo.Session, v *discordgo.VoiceStateUpdate) {
+	// follow first so that the bot is not paused when it moves to its listener
+	c.hub.FollowInvoker(v.GuildID, v.UserID, v.ChannelID)
+	c.hub.CheckListeners(v.GuildID)
 }
 
 type CommandHandleFunc func(s *discordgo.Session, i *discordgo.InteractionCreate)
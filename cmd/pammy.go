@@ -11,13 +11,18 @@ import (
 
 func main() {
 	var token string
+	var cfg pammy.Config
 
 	flag.StringVar(&token, "t", "", "Bot Token")
+	flag.StringVar(&cfg.DataDir, "d", "data", "Data directory for persisting queues, set to empty to disable")
+	flag.BoolVar(&cfg.Resume, "r", false, "Resume playing after restart")
+	flag.DurationVar(&cfg.AutoLeave.EmptyTimeout, "e", 5*time.Minute, "Leave the voice channel after nobody is listening for the duration, set to 0 to disable")
+	flag.DurationVar(&cfg.AutoLeave.IdleTimeout, "i", 15*time.Minute, "Leave the voice channel after waiting for tracks for the duration, set to 0 to disable")
 	flag.Parse()
 
 	rand.Seed(time.Now().UnixNano())
 
-	pammy, err := pammy.NewClient(token)
+	pammy, err := pammy.NewClient(token, cfg)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -1,8 +1,12 @@
 package pammy
 
 import (
+	"errors"
 	"fmt"
 	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/noppawitt/pammy/youtube"
@@ -31,6 +35,12 @@ func (c *AddCommand) Command() *discordgo.ApplicationCommand {
 				Description: "Search term or Youtube URL",
 				Required:    true,
 			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "position",
+				Description: "`end`, `next` or a track No. (default: end)",
+				Required:    false,
+			},
 		},
 	}
 }
@@ -38,26 +48,41 @@ func (c *AddCommand) Command() *discordgo.ApplicationCommand {
 func (c *AddCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	respondText(s, i.Interaction, "Pammy is working...")
 
-	vs := userVoiceState(s.State, i.GuildID, i.Member.User.ID)
-	if vs == nil {
-		updateResponse(s, i.Interaction, "You need to join voice channel first")
+	bot, vs, ok := memberBot(s, i, c.hub)
+	if !ok {
 		return
 	}
 
-	bot, ok := c.hub.GetBot(i.GuildID)
-	if !ok {
-		bot = NewBot(i.GuildID, s, c.ytClient, nil)
-		c.hub.SetBot(bot, i.GuildID)
+	query := i.ApplicationCommandData().Options[0].StringValue()
+
+	position := "end"
+	if len(i.ApplicationCommandData().Options) > 1 {
+		position = strings.ToLower(strings.TrimSpace(i.ApplicationCommandData().Options[1].StringValue()))
 	}
 
-	if bot.VoiceChannelID() != "" && bot.VoiceChannelID() != vs.ChannelID {
-		updateResponse(s, i.Interaction, "Pammy is singing in other voice channel")
-		return
+	trackNo := 0
+	if position != "end" && position != "next" {
+		n, err := strconv.Atoi(position)
+		if err != nil || n < 1 || n > bot.TotalTracks()+1 {
+			updateResponse(s, i.Interaction, "Invalid position: "+position)
+			return
+		}
+		trackNo = n
 	}
 
-	bot.SetTextChannelID(i.ChannelID)
+	if u, err := url.ParseRequestURI(query); err == nil && u.Query().Get("list") != "" {
+		playlist, err := c.ytClient.GetPlaylist(query)
+		if err == nil {
+			c.addPlaylist(s, i, bot, vs, playlist, position, trackNo)
+			return
+		}
 
-	query := i.ApplicationCommandData().Options[0].StringValue()
+		// fall back to the single video when the playlist cannot be loaded (e.g. a mix)
+		if u.Query().Get("v") == "" {
+			updateResponse(s, i.Interaction, "Cannot get playlist")
+			return
+		}
+	}
 
 	var track *Track
 
@@ -70,9 +95,14 @@ func (c *AddCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCreate
 		}
 
 		track = &Track{
-			ID:       video.ID,
-			Name:     video.Title,
-			Duration: video.Duration,
+			ID:            video.ID,
+			Name:          video.Title,
+			Duration:      video.Duration,
+			Live:          video.Live,
+			RequesterID:   i.Member.User.ID,
+			RequesterName: i.Member.User.Username,
+			RequestedAt:   time.Now(),
+			Source:        TrackSourceManual,
 		}
 	} else {
 		video, err := c.ytClient.GetVideo(query)
@@ -82,23 +112,164 @@ func (c *AddCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCreate
 		}
 
 		track = &Track{
-			ID:       video.ID,
-			Name:     video.Title,
-			Duration: video.Duration,
+			ID:            video.ID,
+			Name:          video.Title,
+			Duration:      video.Duration,
+			Live:          youtube.IsLive(video),
+			RequesterID:   i.Member.User.ID,
+			RequesterName: i.Member.User.Username,
+			RequestedAt:   time.Now(),
+			Source:        TrackSourceManual,
 		}
 	}
 
-	if bot.VoiceChannelID() == "" {
-		err := bot.JoinVoiceChannel(vs.ChannelID)
-		if err != nil {
-			updateResponse(s, i.Interaction, "Cannot join voice channel")
-			return
-		}
+	if !joinVoiceChannel(s, i, bot, vs) {
+		return
+	}
+
+	switch position {
+	case "end":
+		err = bot.Add(track)
+	case "next":
+		err = bot.InsertNext(track)
+	default:
+		var idx int
+		idx, err = bot.Insert(trackNo-1, track)
+		trackNo = idx + 1
+	}
+	if err != nil {
+		updateAddError(s, i.Interaction, err, position)
+		return
+	}
+
+	switch position {
+	case "end":
+		updateResponse(s, i.Interaction, fmt.Sprintf("Added `%s`", track.Name))
+	case "next":
+		updateResponse(s, i.Interaction, fmt.Sprintf("Added `%s` to play next", track.Name))
+	default:
+		updateResponse(s, i.Interaction, fmt.Sprintf("Added `%s` as track #%d", track.Name, trackNo))
+	}
+}
+
+func (c *AddCommand) addPlaylist(s *discordgo.Session, i *discordgo.InteractionCreate, bot *Bot, vs *discordgo.VoiceState, playlist youtube.Playlist, position string, trackNo int) {
+	if len(playlist.Videos) == 0 {
+		updateResponse(s, i.Interaction, "Playlist is empty")
+		return
+	}
+
+	tracks := make([]*Track, 0, len(playlist.Videos))
+	now := time.Now()
+	for _, video := range playlist.Videos {
+		tracks = append(tracks, &Track{
+			ID:            video.ID,
+			Name:          video.Title,
+			Duration:      video.Duration,
+			RequesterID:   i.Member.User.ID,
+			RequesterName: i.Member.User.Username,
+			RequestedAt:   now,
+			Source:        TrackSourcePlaylist,
+		})
+	}
+
+	if !joinVoiceChannel(s, i, bot, vs) {
+		return
+	}
+
+	var err error
+	switch position {
+	case "end":
+		err = bot.Add(tracks...)
+	case "next":
+		err = bot.InsertNext(tracks...)
+	default:
+		_, err = bot.Insert(trackNo-1, tracks...)
+	}
+	if err != nil {
+		updateAddError(s, i.Interaction, err, position)
+		return
+	}
+
+	msg := fmt.Sprintf("Added %d tracks from `%s`", len(tracks), playlist.Title)
+	if playlist.Truncated {
+		msg += ", the rest of the playlist cannot be loaded"
+	}
+	updateResponse(s, i.Interaction, msg)
+}
+
+// updateAddError explains why the tracks cannot be added at position.
+func updateAddError(s *discordgo.Session, interaction *discordgo.Interaction, err error, position string) {
+	var limitErr *LimitError
+	switch {
+	case errors.As(err, &limitErr):
+		updateResponse(s, interaction, limitErr.Error())
+	case err == ErrTrackNotFound:
+		updateResponse(s, interaction, "Invalid position: "+position)
+	default:
+		updateResponse(s, interaction, "Cannot add tracks")
+	}
+}
+
+// memberBot returns the bot of the guild for the member who invoked the command, the bot is created when needed.
+// It updates the response and returns false when the member cannot use the bot.
+func memberBot(s *discordgo.Session, i *discordgo.InteractionCreate, hub *Hub) (*Bot, *discordgo.VoiceState, bool) {
+	vs := userVoiceState(s.State, i.GuildID, i.Member.User.ID)
+	if vs == nil {
+		updateResponse(s, i.Interaction, "You need to join voice channel first")
+		return nil, nil, false
+	}
+
+	bot, ok := hub.GetBot(i.GuildID)
+	if !ok {
+		bot = hub.CreateBot(i.GuildID)
+	}
+
+	if bot.VoiceChannelID() != "" && bot.VoiceChannelID() != vs.ChannelID {
+		updateResponse(s, i.Interaction, "Pammy is singing in other voice channel")
+		return nil, nil, false
+	}
+
+	bot.SetTextChannelID(i.ChannelID)
+
+	return bot, vs, true
+}
+
+// joinVoiceChannel joins the member's voice channel if the bot has not joined any channels.
+func joinVoiceChannel(s *discordgo.Session, i *discordgo.InteractionCreate, bot *Bot, vs *discordgo.VoiceState) bool {
+	if bot.VoiceChannelID() != "" {
+		return true
+	}
+
+	err := bot.JoinVoiceChannel(vs.ChannelID)
+	if err != nil {
+		updateResponse(s, i.Interaction, "Cannot join voice channel")
+		return false
+	}
+
+	return true
+}
+
+// voiceChannelListeners returns the IDs of the users in the voice channel, excluding bots.
+func voiceChannelListeners(s *discordgo.State, guildID, channelID string) []string {
+	g, err := s.Guild(guildID)
+	if err != nil {
+		return nil
 	}
 
-	bot.Add(track)
+	var ids []string
+	for _, vs := range g.VoiceStates {
+		if vs.ChannelID != channelID || vs.UserID == s.User.ID {
+			continue
+		}
+
+		if m, err := s.Member(guildID, vs.UserID); err == nil && m.User != nil && m.User.Bot {
+			continue
+		}
+
+		ids = append(ids, vs.UserID)
+	}
 
-	updateResponse(s, i.Interaction, fmt.Sprintf("Added `%s`", track.Name))
+	return ids
 }
 
 func userVoiceState(s *discordgo.State, guildID, userID string) *discordgo.VoiceState {
@@ -116,6 +287,52 @@ func userVoiceState(s *discordgo.State, guildID, userID string) *discordgo.Voice
 	return nil
 }
 
+type VoteSkipCommand struct {
+	hub *Hub
+}
+
+func NewVoteSkipCommand(hub *Hub) *VoteSkipCommand {
+	return &VoteSkipCommand{
+		hub: hub,
+	}
+}
+
+func (c *VoteSkipCommand) Command() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "voteskip",
+		Description: "Vote to skip the playing track",
+	}
+}
+
+func (c *VoteSkipCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	bot, ok := c.hub.GetBot(i.GuildID)
+	if !ok {
+		respondAddMusicFirst(s, i.Interaction)
+		return
+	}
+
+	vs := userVoiceState(s.State, i.GuildID, i.Member.User.ID)
+	if vs == nil || vs.ChannelID != bot.VoiceChannelID() {
+		respondTextPrivate(s, i.Interaction, "You need to join Pammy's voice channel to vote")
+		return
+	}
+
+	listeners := voiceChannelListeners(s.State, i.GuildID, bot.VoiceChannelID())
+
+	votes, required, skipped, err := bot.VoteSkip(i.Member.User.ID, listeners)
+	if err != nil {
+		respondTextPrivate(s, i.Interaction, "No music playing")
+		return
+	}
+
+	if skipped {
+		respondText(s, i.Interaction, fmt.Sprintf("Skipped by vote (%d/%d)", votes, required))
+		return
+	}
+
+	respondText(s, i.Interaction, fmt.Sprintf("Voted to skip (%d/%d)", votes, required))
+}
+
 type NextCommand struct {
 	hub *Hub
 }
@@ -153,18 +370,17 @@ func (c *NextCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCreat
 		n = int(i.ApplicationCommandData().Options[0].IntValue())
 	}
 
-	err := bot.Next(n)
+	idx, err := bot.Next(n)
 	if err != nil {
 		respondTextPrivate(s, i.Interaction, "Cannot skip next")
 		return
 	}
 
-	currentTrackIdx := bot.CurrentTrackIndex()
-
-	if currentTrackIdx >= bot.TotalTracks() {
+	// a discovered track is added at the end of the queue
+	if idx >= bot.TotalTracks() {
 		respondText(s, i.Interaction, "End of queue")
 	} else {
-		respondText(s, i.Interaction, fmt.Sprintf("Skipped to track #%d", currentTrackIdx+1))
+		respondText(s, i.Interaction, fmt.Sprintf("Skipped to track #%d", idx+1))
 	}
 }
 
@@ -292,6 +508,56 @@ func (c *RemoveCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCre
 	respondText(s, i.Interaction, fmt.Sprintf("Removed track #%d", trackNo))
 }
 
+type MoveCommand struct {
+	hub *Hub
+}
+
+func NewMoveCommand(hub *Hub) *MoveCommand {
+	return &MoveCommand{
+		hub: hub,
+	}
+}
+
+func (c *MoveCommand) Command() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "move",
+		Description: "Move track to another position in the playlist",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "track-no",
+				Description: "Track No.",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "to",
+				Description: "New track No.",
+				Required:    true,
+			},
+		},
+	}
+}
+
+func (c *MoveCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	bot, ok := c.hub.GetBot(i.GuildID)
+	if !ok {
+		respondTextPrivate(s, i.Interaction, "Pammy didn't join any channels")
+		return
+	}
+
+	trackNo := int(i.ApplicationCommandData().Options[0].IntValue())
+	to := int(i.ApplicationCommandData().Options[1].IntValue())
+
+	err := bot.Move(trackNo-1, to-1)
+	if err != nil {
+		respondTextPrivate(s, i.Interaction, "Track not found")
+		return
+	}
+
+	respondText(s, i.Interaction, fmt.Sprintf("Moved track #%d to #%d", trackNo, to))
+}
+
 type ClearCommand struct {
 	hub *Hub
 }
@@ -481,6 +747,847 @@ func (c *AutoPlayCommand) Handle(s *discordgo.Session, i *discordgo.InteractionC
 	respondText(s, i.Interaction, msg)
 }
 
+type FairQueueCommand struct {
+	hub *Hub
+}
+
+func NewFairQueueCommand(hub *Hub) *FairQueueCommand {
+	return &FairQueueCommand{
+		hub: hub,
+	}
+}
+
+func (c *FairQueueCommand) Command() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "fairqueue",
+		Description: "Take turns playing the tracks of each requester",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "enabled",
+				Description: "Enable or disable fair queue",
+				Required:    true,
+			},
+		},
+	}
+}
+
+func (c *FairQueueCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	enabled := i.ApplicationCommandData().Options[0].BoolValue()
+
+	err := c.hub.UpdateSettings(i.GuildID, func(settings *Settings) {
+		settings.FairQueue = enabled
+	})
+	if err != nil {
+		respondTextPrivate(s, i.Interaction, "Cannot save settings")
+		return
+	}
+
+	msg := "Fair queue is "
+	if enabled {
+		msg += "enabled"
+	} else {
+		msg += "disabled"
+	}
+
+	respondText(s, i.Interaction, msg)
+}
+
+type ShuffleCommand struct {
+	hub *Hub
+}
+
+func NewShuffleCommand(hub *Hub) *ShuffleCommand {
+	return &ShuffleCommand{
+		hub: hub,
+	}
+}
+
+func (c *ShuffleCommand) Command() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "shuffle",
+		Description: "Shuffle the upcoming tracks",
+	}
+}
+
+func (c *ShuffleCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	bot, ok := c.hub.GetBot(i.GuildID)
+	if !ok {
+		respondAddMusicFirst(s, i.Interaction)
+		return
+	}
+
+	err := bot.Shuffle()
+	if err != nil {
+		respondTextPrivate(s, i.Interaction, "No upcoming tracks to shuffle")
+		return
+	}
+
+	respondText(s, i.Interaction, "Shuffled the upcoming tracks")
+}
+
+type UnshuffleCommand struct {
+	hub *Hub
+}
+
+func NewUnshuffleCommand(hub *Hub) *UnshuffleCommand {
+	return &UnshuffleCommand{
+		hub: hub,
+	}
+}
+
+func (c *UnshuffleCommand) Command() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "unshuffle",
+		Description: "Restore the upcoming tracks to the original order",
+	}
+}
+
+func (c *UnshuffleCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	bot, ok := c.hub.GetBot(i.GuildID)
+	if !ok {
+		respondAddMusicFirst(s, i.Interaction)
+		return
+	}
+
+	err := bot.Unshuffle()
+	if err != nil {
+		respondTextPrivate(s, i.Interaction, "Tracks are not shuffled")
+		return
+	}
+
+	respondText(s, i.Interaction, "Restored the original order")
+}
+
+type LoopCommand struct {
+	hub *Hub
+}
+
+func NewLoopCommand(hub *Hub) *LoopCommand {
+	return &LoopCommand{
+		hub: hub,
+	}
+}
+
+func (c *LoopCommand) Command() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "loop",
+		Description: "Repeat the playing track or the whole queue",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "mode",
+				Description: "Loop mode",
+				Required:    true,
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: "off", Value: int(LoopModeOff)},
+					{Name: "track", Value: int(LoopModeTrack)},
+					{Name: "queue", Value: int(LoopModeQueue)},
+				},
+			},
+		},
+	}
+}
+
+func (c *LoopCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	bot, ok := c.hub.GetBot(i.GuildID)
+	if !ok {
+		respondAddMusicFirst(s, i.Interaction)
+		return
+	}
+
+	mode := LoopMode(i.ApplicationCommandData().Options[0].IntValue())
+
+	bot.SetLoopMode(mode)
+
+	respondText(s, i.Interaction, fmt.Sprintf("Loop mode is `%s`", mode))
+}
+
+type SeekCommand struct {
+	hub *Hub
+}
+
+func NewSeekCommand(hub *Hub) *SeekCommand {
+	return &SeekCommand{
+		hub: hub,
+	}
+}
+
+func (c *SeekCommand) Command() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "seek",
+		Description: "Seek to a position in the playing track",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "position",
+				Description: "Position such as `1:23`, `+30s` or `-10s`",
+				Required:    true,
+			},
+		},
+	}
+}
+
+func (c *SeekCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	bot, ok := c.hub.GetBot(i.GuildID)
+	if !ok {
+		respondAddMusicFirst(s, i.Interaction)
+		return
+	}
+
+	position := strings.TrimSpace(i.ApplicationCommandData().Options[0].StringValue())
+
+	d, relative, err := parseSeekPosition(position)
+	if err != nil {
+		respondTextPrivate(s, i.Interaction, "Invalid position: "+position)
+		return
+	}
+
+	if relative {
+		err = bot.SeekRelative(d)
+	} else {
+		err = bot.Seek(d)
+	}
+
+	switch err {
+	case nil:
+		respondText(s, i.Interaction, "Seeked to "+position)
+	case ErrNotPlaying:
+		respondTextPrivate(s, i.Interaction, "No music playing")
+	case ErrSeekOutOfRange:
+		respondTextPrivate(s, i.Interaction, "Position is beyond the end of the track")
+	case ErrCannotSeekLive:
+		respondTextPrivate(s, i.Interaction, "Cannot seek in a live stream")
+	default:
+		respondTextPrivate(s, i.Interaction, "Cannot seek")
+	}
+}
+
+// parseSeekPosition parses an absolute position (`1:23`, `90`, `1m30s`)
+// or a relative offset prefixed with a sign (`+30s`, `-10s`, `+1:00`).
+func parseSeekPosition(s string) (d time.Duration, relative bool, err error) {
+	sign := time.Duration(1)
+	if strings.HasPrefix(s, "+") || strings.HasPrefix(s, "-") {
+		relative = true
+		if s[0] == '-' {
+			sign = -1
+		}
+		s = s[1:]
+	}
+
+	d, err = youtube.ParseLengthText(s)
+	if err != nil {
+		d, err = time.ParseDuration(s)
+		if err != nil || d < 0 {
+			return 0, false, fmt.Errorf("invalid seek position: %q", s)
+		}
+	}
+
+	return sign * d, relative, nil
+}
+
+type NowPlayingCommand struct {
+	hub *Hub
+}
+
+func NewNowPlayingCommand(hub *Hub) *NowPlayingCommand {
+	return &NowPlayingCommand{
+		hub: hub,
+	}
+}
+
+func (c *NowPlayingCommand) Command() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "nowplaying",
+		Description: "Show the playing track",
+	}
+}
+
+func (c *NowPlayingCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	bot, ok := c.hub.GetBot(i.GuildID)
+	if !ok {
+		respondAddMusicFirst(s, i.Interaction)
+		return
+	}
+
+	track, ok := bot.CurrentTrack()
+	if !ok {
+		respondTextPrivate(s, i.Interaction, "No music playing")
+		return
+	}
+
+	position := bot.Position()
+
+	msg := fmt.Sprintf("Playing `%s`", track.Name)
+	if bot.State() == BotStatePaused {
+		msg = fmt.Sprintf("Paused `%s`", track.Name)
+	}
+
+	if track.Live {
+		msg += fmt.Sprintf("\n`LIVE` %s", formatDuration(position))
+	} else {
+		msg += fmt.Sprintf("\n`%s` %s / %s", progressBar(position, track.Duration, 20), formatDuration(position), formatDuration(track.Duration))
+	}
+
+	respondText(s, i.Interaction, msg)
+}
+
+// progressBar renders the elapsed portion of the total duration as a bar with the given width.
+func progressBar(elapsed, total time.Duration, width int) string {
+	filled := 0
+	if total > 0 {
+		filled = int(int64(width) * int64(elapsed) / int64(total))
+	}
+
+	if filled > width {
+		filled = width
+	}
+
+	return "[" + strings.Repeat("=", filled) + strings.Repeat("-", width-filled) + "]"
+}
+
+// formatDuration formats the duration as `m:ss` or `h:mm:ss`.
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+
+	h := int(d / time.Hour)
+	m := int(d % time.Hour / time.Minute)
+	sec := int(d % time.Minute / time.Second)
+
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, sec)
+	}
+
+	return fmt.Sprintf("%d:%02d", m, sec)
+}
+
+type VolumeCommand struct {
+	hub *Hub
+}
+
+func NewVolumeCommand(hub *Hub) *VolumeCommand {
+	return &VolumeCommand{
+		hub: hub,
+	}
+}
+
+func (c *VolumeCommand) Command() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "volume",
+		Description: "Set the player volume",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "percent",
+				Description: "Volume in percent (0-200)",
+				Required:    true,
+			},
+		},
+	}
+}
+
+func (c *VolumeCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	bot, ok := c.hub.GetBot(i.GuildID)
+	if !ok {
+		respondAddMusicFirst(s, i.Interaction)
+		return
+	}
+
+	v := int(i.ApplicationCommandData().Options[0].IntValue())
+
+	err := bot.SetVolume(v)
+	if err != nil {
+		respondTextPrivate(s, i.Interaction, "Volume must be between 0 and 200")
+		return
+	}
+
+	respondText(s, i.Interaction, fmt.Sprintf("Volume is %d%%", v))
+}
+
+type FilterCommand struct {
+	hub *Hub
+}
+
+func NewFilterCommand(hub *Hub) *FilterCommand {
+	return &FilterCommand{
+		hub: hub,
+	}
+}
+
+func (c *FilterCommand) Command() *discordgo.ApplicationCommand {
+	choices := []*discordgo.ApplicationCommandOptionChoice{
+		{Name: "off", Value: "off"},
+	}
+	for _, f := range AudioFilters {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: f.Name, Value: f.Name})
+	}
+
+	return &discordgo.ApplicationCommand{
+		Name:        "filter",
+		Description: "Toggle an audio filter",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "preset",
+				Description: "Filter preset, `off` disables all filters",
+				Required:    true,
+				Choices:     choices,
+			},
+		},
+	}
+}
+
+func (c *FilterCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	bot, ok := c.hub.GetBot(i.GuildID)
+	if !ok {
+		respondAddMusicFirst(s, i.Interaction)
+		return
+	}
+
+	name := i.ApplicationCommandData().Options[0].StringValue()
+
+	if name == "off" {
+		bot.ClearFilters()
+		respondText(s, i.Interaction, "Filters are disabled")
+		return
+	}
+
+	_, err := bot.ToggleFilter(name)
+	if err != nil {
+		respondTextPrivate(s, i.Interaction, "Filter not found")
+		return
+	}
+
+	filters := bot.Filters()
+	if len(filters) == 0 {
+		respondText(s, i.Interaction, "Filters are disabled")
+		return
+	}
+
+	respondText(s, i.Interaction, fmt.Sprintf("Filters: `%s`", strings.Join(filters, ", ")))
+}
+
+type CrossfadeCommand struct {
+	hub *Hub
+}
+
+func NewCrossfadeCommand(hub *Hub) *CrossfadeCommand {
+	return &CrossfadeCommand{
+		hub: hub,
+	}
+}
+
+func (c *CrossfadeCommand) Command() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "crossfade",
+		Description: "Fade the end of each track into the next track",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "seconds",
+				Description: "Crossfade duration (0-12), 0 disables crossfading",
+				Required:    true,
+			},
+		},
+	}
+}
+
+func (c *CrossfadeCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	bot, ok := c.hub.GetBot(i.GuildID)
+	if !ok {
+		respondAddMusicFirst(s, i.Interaction)
+		return
+	}
+
+	seconds := i.ApplicationCommandData().Options[0].IntValue()
+
+	err := bot.SetCrossfade(time.Duration(seconds) * time.Second)
+	if err != nil {
+		respondTextPrivate(s, i.Interaction, "Crossfade must be between 0 and 12 seconds")
+		return
+	}
+
+	if seconds == 0 {
+		respondText(s, i.Interaction, "Crossfade is disabled")
+		return
+	}
+
+	respondText(s, i.Interaction, fmt.Sprintf("Crossfade is %d seconds", seconds))
+}
+
+type SettingsCommand struct {
+	hub *Hub
+}
+
+func NewSettingsCommand(hub *Hub) *SettingsCommand {
+	return &SettingsCommand{
+		hub: hub,
+	}
+}
+
+func (c *SettingsCommand) Command() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "settings",
+		Description: "Change the player settings of this server",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "voteskip",
+				Description: "Set the percentage of listeners needed to skip a track by voting",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "percent",
+						Description: "Percentage of listeners (1-100)",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "djrole",
+				Description: "Restrict clearing, resetting and leaving the player to a role",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionRole,
+						Name:        "role",
+						Description: "DJ role, leave empty to allow everyone",
+						Required:    false,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "limits",
+				Description: "Limit the tracks that can be added, 0 means no limit",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "user_tracks",
+						Description: "Maximum upcoming tracks per member",
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "track_minutes",
+						Description: "Maximum duration of a track in minutes",
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "queue_length",
+						Description: "Maximum upcoming tracks",
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "normalize",
+				Description: "Play tracks at a consistent loudness",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "enabled",
+						Description: "Enable or disable loudness normalization",
+						Required:    true,
+					},
+				},
+			},
+		},
+	}
+}
+
+func (c *SettingsCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	sub := i.ApplicationCommandData().Options[0]
+
+	var (
+		update func(*Settings)
+		msg    string
+	)
+
+	switch sub.Name {
+	case "normalize":
+		enabled := sub.Options[0].BoolValue()
+		update = func(settings *Settings) {
+			settings.Normalize = enabled
+		}
+
+		msg = "Loudness normalization is "
+		if enabled {
+			msg += "enabled"
+		} else {
+			msg += "disabled"
+		}
+	case "limits":
+		for _, opt := range sub.Options {
+			if opt.IntValue() < 0 {
+				respondTextPrivate(s, i.Interaction, "Limits cannot be negative")
+				return
+			}
+		}
+
+		update = func(settings *Settings) {
+			for _, opt := range sub.Options {
+				switch opt.Name {
+				case "user_tracks":
+					settings.MaxUserTracks = int(opt.IntValue())
+				case "track_minutes":
+					settings.MaxTrackDuration = time.Duration(opt.IntValue()) * time.Minute
+				case "queue_length":
+					settings.MaxQueueLength = int(opt.IntValue())
+				}
+			}
+		}
+
+		limits := c.hub.Settings(i.GuildID)
+		update(&limits)
+		msg = limitsText(limits)
+	case "voteskip":
+		percent := sub.Options[0].IntValue()
+		if percent < 1 || percent > 100 {
+			respondTextPrivate(s, i.Interaction, "Percentage must be between 1 and 100")
+			return
+		}
+
+		update = func(settings *Settings) {
+			settings.VoteSkipRatio = float64(percent) / 100
+		}
+
+		msg = fmt.Sprintf("Skipping by vote needs %d%% of listeners", percent)
+	case "djrole":
+		var roleID string
+		if len(sub.Options) > 0 {
+			roleID = sub.Options[0].RoleValue(nil, "").ID
+		}
+
+		update = func(settings *Settings) {
+			settings.DJRoleID = roleID
+		}
+
+		if roleID == "" {
+			msg = "Everyone can control the player"
+		} else {
+			msg = fmt.Sprintf("Only <@&%s> can clear, reset and leave the player", roleID)
+		}
+	default:
+		return
+	}
+
+	err := c.hub.UpdateSettings(i.GuildID, update)
+	if err != nil {
+		respondTextPrivate(s, i.Interaction, "Cannot save settings")
+		return
+	}
+
+	respondText(s, i.Interaction, msg)
+}
+
+func limitsText(settings Settings) string {
+	limit := func(n int) string {
+		if n == 0 {
+			return "no limit"
+		}
+		return strconv.Itoa(n)
+	}
+
+	duration := "no limit"
+	if settings.MaxTrackDuration > 0 {
+		duration = formatDuration(settings.MaxTrackDuration)
+	}
+
+	return fmt.Sprintf("Tracks per member: %s\nTrack duration: %s\nQueue length: %s",
+		limit(settings.MaxUserTracks), duration, limit(settings.MaxQueueLength))
+}
+
+type PlaylistCommand struct {
+	hub   *Hub
+	store *Store
+}
+
+func NewPlaylistCommand(hub *Hub, store *Store) *PlaylistCommand {
+	return &PlaylistCommand{
+		hub:   hub,
+		store: store,
+	}
+}
+
+func (c *PlaylistCommand) Command() *discordgo.ApplicationCommand {
+	scopeOption := &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionString,
+		Name:        "scope",
+		Description: "Playlists of this server or your own playlists (default: server)",
+		Required:    false,
+		Choices: []*discordgo.ApplicationCommandOptionChoice{
+			{Name: "server", Value: "guild"},
+			{Name: "me", Value: "user"},
+		},
+	}
+	nameOption := &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionString,
+		Name:        "name",
+		Description: "Playlist name",
+		Required:    true,
+	}
+
+	return &discordgo.ApplicationCommand{
+		Name:        "playlist",
+		Description: "Manage saved playlists",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "save",
+				Description: "Save all tracks as a playlist",
+				Options:     []*discordgo.ApplicationCommandOption{nameOption, scopeOption},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "load",
+				Description: "Add tracks from a saved playlist",
+				Options:     []*discordgo.ApplicationCommandOption{nameOption, scopeOption},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "list",
+				Description: "List saved playlists",
+				Options:     []*discordgo.ApplicationCommandOption{scopeOption},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "delete",
+				Description: "Delete a saved playlist",
+				Options:     []*discordgo.ApplicationCommandOption{nameOption, scopeOption},
+			},
+		},
+	}
+}
+
+func (c *PlaylistCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	sub := i.ApplicationCommandData().Options[0]
+
+	var name string
+	scope := GuildPlaylistScope(i.GuildID)
+	for _, opt := range sub.Options {
+		switch opt.Name {
+		case "name":
+			name = strings.TrimSpace(opt.StringValue())
+		case "scope":
+			if opt.StringValue() == "user" {
+				scope = UserPlaylistScope(i.Member.User.ID)
+			}
+		}
+	}
+
+	switch sub.Name {
+	case "save":
+		c.save(s, i, scope, name)
+	case "load":
+		c.load(s, i, scope, name)
+	case "list":
+		c.list(s, i, scope)
+	case "delete":
+		c.delete(s, i, scope, name)
+	}
+}
+
+func (c *PlaylistCommand) save(s *discordgo.Session, i *discordgo.InteractionCreate, scope PlaylistScope, name string) {
+	bot, ok := c.hub.GetBot(i.GuildID)
+	if !ok || bot.TotalTracks() == 0 {
+		respondAddMusicFirst(s, i.Interaction)
+		return
+	}
+
+	tracks := bot.Tracks()
+
+	err := c.store.SavePlaylist(scope, SavedPlaylist{Name: name, Tracks: tracks})
+	if err != nil {
+		respondPlaylistError(s, i.Interaction, err)
+		return
+	}
+
+	respondText(s, i.Interaction, fmt.Sprintf("Saved %d tracks to playlist `%s`", len(tracks), name))
+}
+
+func (c *PlaylistCommand) load(s *discordgo.Session, i *discordgo.InteractionCreate, scope PlaylistScope, name string) {
+	respondText(s, i.Interaction, "Pammy is working...")
+
+	playlist, err := c.store.LoadPlaylist(scope, name)
+	if err != nil {
+		switch err {
+		case ErrPlaylistNotFound:
+			updateResponse(s, i.Interaction, fmt.Sprintf("Playlist `%s` not found", name))
+		case ErrInvalidPlaylistName:
+			updateResponse(s, i.Interaction, "Invalid playlist name")
+		default:
+			updateResponse(s, i.Interaction, "Cannot load playlist")
+		}
+		return
+	}
+
+	if len(playlist.Tracks) == 0 {
+		updateResponse(s, i.Interaction, "Playlist is empty")
+		return
+	}
+
+	bot, vs, ok := memberBot(s, i, c.hub)
+	if !ok {
+		return
+	}
+
+	if !joinVoiceChannel(s, i, bot, vs) {
+		return
+	}
+
+	now := time.Now()
+	for _, track := range playlist.Tracks {
+		track.RequesterID = i.Member.User.ID
+		track.RequesterName = i.Member.User.Username
+		track.RequestedAt = now
+		track.Source = TrackSourcePlaylist
+	}
+
+	err = bot.Add(playlist.Tracks...)
+	if err != nil {
+		updateAddError(s, i.Interaction, err, "end")
+		return
+	}
+
+	updateResponse(s, i.Interaction, fmt.Sprintf("Added %d tracks from playlist `%s`", len(playlist.Tracks), playlist.Name))
+}
+
+func (c *PlaylistCommand) list(s *discordgo.Session, i *discordgo.InteractionCreate, scope PlaylistScope) {
+	names, err := c.store.ListPlaylists(scope)
+	if err != nil {
+		respondPlaylistError(s, i.Interaction, err)
+		return
+	}
+
+	if len(names) == 0 {
+		respondText(s, i.Interaction, "No playlists")
+		return
+	}
+
+	respondText(s, i.Interaction, "Playlists:\n```"+strings.Join(names, "\n")+"```")
+}
+
+func (c *PlaylistCommand) delete(s *discordgo.Session, i *discordgo.InteractionCreate, scope PlaylistScope, name string) {
+	err := c.store.DeletePlaylist(scope, name)
+	if err != nil {
+		respondPlaylistError(s, i.Interaction, err)
+		return
+	}
+
+	respondText(s, i.Interaction, fmt.Sprintf("Deleted playlist `%s`", name))
+}
+
+func respondPlaylistError(s *discordgo.Session, i *discordgo.Interaction, err error) error {
+	switch err {
+	case ErrPlaylistNotFound:
+		return respondTextPrivate(s, i, "Playlist not found")
+	case ErrInvalidPlaylistName:
+		return respondTextPrivate(s, i, "Invalid playlist name")
+	default:
+		return respondTextPrivate(s, i, "Cannot access playlists")
+	}
+}
+
 func respondAddMusicFirst(s *discordgo.Session, i *discordgo.Interaction) error {
 	return respondTextPrivate(s, i, "Add music with `/play {search-term}` first")
 }
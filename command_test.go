@@ -0,0 +1,40 @@
+package pammy
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSeekPosition(t *testing.T) {
+	tests := []struct {
+		s            string
+		want         time.Duration
+		wantRelative bool
+		wantErr      bool
+	}{
+		{s: "1:23", want: 83 * time.Second},
+		{s: "1:02:03", want: time.Hour + 2*time.Minute + 3*time.Second},
+		{s: "90", want: 90 * time.Second},
+		{s: "1m30s", want: 90 * time.Second},
+		{s: "+30s", want: 30 * time.Second, wantRelative: true},
+		{s: "-10s", want: -10 * time.Second, wantRelative: true},
+		{s: "+1:00", want: time.Minute, wantRelative: true},
+		{s: "abc", wantErr: true},
+		{s: "", wantErr: true},
+		{s: "+", wantErr: true},
+		{s: "1:-2", wantErr: true},
+		{s: "--10s", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, relative, err := parseSeekPosition(tt.s)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: error = %v, want error %v", tt.s, err, tt.wantErr)
+			continue
+		}
+
+		if got != tt.want || relative != tt.wantRelative {
+			t.Errorf("%q: got %v, relative %v, want %v, relative %v", tt.s, got, relative, tt.want, tt.wantRelative)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package pammy
+
+import (
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jonas747/ogg"
+	"github.com/noppawitt/dca"
+)
+
+// opusEncoder encodes PCM from the mixer to opus frames with ffmpeg.
+// It implements dca.OpusReader so that it can be streamed with dca.NewStream.
+type opusEncoder struct {
+	cmd    *exec.Cmd
+	frames chan []byte
+	opts   *dca.EncodeOptions
+
+	stopOnce sync.Once
+}
+
+func newOpusEncoder(r io.Reader, opts *dca.EncodeOptions) (*opusEncoder, error) {
+	err := opts.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	vbr := "on"
+	if !opts.VBR {
+		vbr = "off"
+	}
+
+	cmd := exec.Command("ffmpeg",
+		"-f", "s16le",
+		"-ar", strconv.Itoa(pcmSampleRate),
+		"-ac", strconv.Itoa(pcmChannels),
+		"-i", "pipe:0",
+		"-acodec", "libopus",
+		"-f", "ogg",
+		"-vbr", vbr,
+		"-compression_level", strconv.Itoa(opts.CompressionLevel),
+		"-ar", strconv.Itoa(opts.FrameRate),
+		"-ac", strconv.Itoa(opts.Channels),
+		"-b:a", strconv.Itoa(opts.Bitrate*1000),
+		"-application", string(opts.Application),
+		"-frame_duration", strconv.Itoa(opts.FrameDuration),
+		"-packet_loss", strconv.Itoa(opts.PacketLoss),
+		"-threads", strconv.Itoa(opts.Threads),
+		"pipe:1",
+	)
+	cmd.Stdin = r
+	cmd.Stderr = ioutil.Discard
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		return nil, err
+	}
+
+	e := &opusEncoder{
+		cmd:    cmd,
+		frames: make(chan []byte, opts.BufferedFrames),
+		opts:   opts,
+	}
+
+	go e.readFrames(stdout)
+
+	return e, nil
+}
+
+func (e *opusEncoder) readFrames(stdout io.Reader) {
+	defer close(e.frames)
+
+	decoder := ogg.NewPacketDecoder(ogg.NewDecoder(stdout))
+
+	// the first 2 packets are ogg opus metadata
+	skipPackets := 2
+	for {
+		packet, _, err := decoder.Decode()
+		if err != nil {
+			break
+		}
+
+		if skipPackets > 0 {
+			skipPackets--
+			continue
+		}
+
+		e.frames <- packet
+	}
+
+	e.cmd.Wait()
+}
+
+// OpusFrame returns the next opus frame, io.EOF is returned when the input ends or the encoder is stopped.
+func (e *opusEncoder) OpusFrame() ([]byte, error) {
+	frame, ok := <-e.frames
+	if !ok {
+		return nil, io.EOF
+	}
+
+	return frame, nil
+}
+
+func (e *opusEncoder) FrameDuration() time.Duration {
+	return time.Duration(e.opts.FrameDuration) * time.Millisecond
+}
+
+// Stop kills ffmpeg and drops the buffered frames.
+func (e *opusEncoder) Stop() {
+	e.stopOnce.Do(func() {
+		e.cmd.Process.Kill()
+
+		go func() {
+			for range e.frames {
+			}
+		}()
+	})
+}
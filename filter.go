@@ -0,0 +1,51 @@
+package pammy
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrFilterNotFound = errors.New("filter not found")
+
+// AudioFilter is a preset of ffmpeg audio filters, see https://ffmpeg.org/ffmpeg-filters.html#Audio-Filters
+type AudioFilter struct {
+	Name  string
+	Chain string
+	// Speed is the playback speed factor the filter applies to the source.
+	Speed float64
+}
+
+// AudioFilters are the available filter presets.
+// Filters changing the sample rate resample to 48kHz first so that the speed does not depend on the source format.
+var AudioFilters = []AudioFilter{
+	{Name: "bassboost", Chain: "bass=g=10", Speed: 1},
+	{Name: "nightcore", Chain: "aresample=48000,asetrate=60000,aresample=48000", Speed: 1.25},
+	{Name: "vaporwave", Chain: "aresample=48000,asetrate=38400,aresample=48000", Speed: 0.8},
+	{Name: "8d", Chain: "apulsator=hz=0.125", Speed: 1},
+}
+
+func findAudioFilter(name string) (AudioFilter, bool) {
+	for _, f := range AudioFilters {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return AudioFilter{}, false
+}
+
+// audioFilterChain returns the ffmpeg filter chain of the filters and their combined speed factor.
+func audioFilterChain(names []string) (string, float64) {
+	var chains []string
+	speed := 1.0
+
+	for _, name := range names {
+		f, ok := findAudioFilter(name)
+		if !ok {
+			continue
+		}
+		chains = append(chains, f.Chain)
+		speed *= f.Speed
+	}
+
+	return strings.Join(chains, ","), speed
+}
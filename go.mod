@@ -4,6 +4,7 @@ go 1.16
 
 require (
 	github.com/bwmarrin/discordgo v0.23.3-0.20210821175000-0fad116c6c2a
+	github.com/jonas747/ogg v0.0.0-20161220051205-b4f6f4cf3757
 	github.com/kkdai/youtube/v2 v2.7.4
 	github.com/noppawitt/dca v0.0.0-20210930062043-8ee4ff4c0b89
 	github.com/tidwall/gjson v1.9.1
@@ -1,7 +1,9 @@
 package pammy
 
 import (
+	"log"
 	"sync"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/noppawitt/pammy/youtube"
@@ -9,16 +11,45 @@ import (
 
 type Hub struct {
 	mu sync.RWMutex
+	// settingsMu serializes updating the settings so that concurrent updates are not lost.
+	settingsMu sync.Mutex
 
-	bots map[string]*Bot
+	bots     map[string]*Bot
+	settings map[string]Settings
+	idle     map[string]*idleBot
+
+	dg       *discordgo.Session
+	ytClient *youtube.Client
+	store    *Store
+
+	autoLeave AutoLeave
+
+	closeCh chan struct{}
 }
 
-func NewHub(dg *discordgo.Session, ytClient *youtube.Client) *Hub {
+// NewHub creates a hub, the bot states are not persisted when store is nil.
+func NewHub(dg *discordgo.Session, ytClient *youtube.Client, store *Store, autoLeave AutoLeave) *Hub {
 	return &Hub{
-		bots: make(map[string]*Bot),
+		bots:      make(map[string]*Bot),
+		settings:  make(map[string]Settings),
+		idle:      make(map[string]*idleBot),
+		dg:        dg,
+		ytClient:  ytClient,
+		store:     store,
+		autoLeave: autoLeave,
+		closeCh:   make(chan struct{}),
 	}
 }
 
+// CreateBot creates a bot with the settings of the guild and adds it to the hub.
+func (h *Hub) CreateBot(guildID string) *Bot {
+	bot := NewBot(guildID, h.dg, h.ytClient, nil)
+	bot.SetSettings(h.Settings(guildID))
+	h.SetBot(bot, guildID)
+
+	return bot
+}
+
 func (h *Hub) SetBot(bot *Bot, guildID string) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
@@ -34,8 +65,173 @@ func (h *Hub) GetBot(guildID string) (*Bot, bool) {
 	return bot, ok
 }
 
+// copyBots copies the bots by guild ID so that their methods can be called without holding h.mu.
+func (h *Hub) copyBots() map[string]*Bot {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	bots := make(map[string]*Bot, len(h.bots))
+	for guildID, bot := range h.bots {
+		bots[guildID] = bot
+	}
+	return bots
+}
+
 func (h *Hub) RemoveBot(guildID string) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 	delete(h.bots, guildID)
+	delete(h.idle, guildID)
+
+	if h.store != nil {
+		err := h.store.DeleteBot(guildID)
+		if err != nil {
+			log.Println("cannot delete bot state: ", err)
+		}
+	}
+}
+
+// FollowInvoker moves the bot of the guild to channelID when the member who ran the last command moved there
+// and nobody else is listening to the bot.
+func (h *Hub) FollowInvoker(guildID, userID, channelID string) {
+	bot, ok := h.GetBot(guildID)
+	if !ok || channelID == "" || userID != bot.LastInvokerID() {
+		return
+	}
+
+	current := bot.VoiceChannelID()
+	if current == "" || current == channelID {
+		return
+	}
+
+	if len(voiceChannelListeners(h.dg.State, guildID, current)) > 0 {
+		return
+	}
+
+	err := bot.MoveVoiceChannel(channelID)
+	if err != nil {
+		log.Printf("cannot follow member to voice channel in guild %s: %v", guildID, err)
+	}
+}
+
+// Settings returns the settings of the guild.
+func (h *Hub) Settings(guildID string) Settings {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	settings, ok := h.settings[guildID]
+	if ok {
+		return settings
+	}
+
+	settings = DefaultSettings()
+	if h.store != nil {
+		var err error
+		settings, err = h.store.LoadSettings(guildID)
+		if err != nil {
+			log.Println("cannot load settings: ", err)
+			settings = DefaultSettings()
+		}
+	}
+
+	h.settings[guildID] = settings
+
+	return settings
+}
+
+// UpdateSettings changes the settings of the guild with fn, persists them and applies them to the bot of the guild.
+func (h *Hub) UpdateSettings(guildID string, fn func(*Settings)) error {
+	h.settingsMu.Lock()
+	defer h.settingsMu.Unlock()
+
+	settings := h.Settings(guildID)
+	fn(&settings)
+
+	if h.store != nil {
+		err := h.store.SaveSettings(guildID, settings)
+		if err != nil {
+			return err
+		}
+	}
+
+	h.mu.Lock()
+	h.settings[guildID] = settings
+	bot, ok := h.bots[guildID]
+	h.mu.Unlock()
+
+	if ok {
+		bot.SetSettings(settings)
+	}
+
+	return nil
+}
+
+// Restore recreates the bots from the store.
+// Set resume to true to rejoin the voice channels and continue the tracks that were playing.
+func (h *Hub) Restore(resume bool) error {
+	if h.store == nil {
+		return nil
+	}
+
+	snapshots, err := h.store.LoadBots()
+	if err != nil {
+		return err
+	}
+
+	for _, snapshot := range snapshots {
+		bot := h.CreateBot(snapshot.GuildID)
+		bot.Restore(snapshot)
+
+		if !resume || !snapshot.Playing || snapshot.VoiceChannelID == "" {
+			continue
+		}
+
+		err := bot.JoinVoiceChannel(snapshot.VoiceChannelID)
+		if err != nil {
+			log.Printf("cannot rejoin voice channel in guild %s: %v", snapshot.GuildID, err)
+			continue
+		}
+
+		err = bot.GoTo(bot.CurrentTrackIndex())
+		if err != nil {
+			log.Printf("cannot resume playing in guild %s: %v", snapshot.GuildID, err)
+		}
+	}
+
+	return nil
+}
+
+// Save persists the states of all bots.
+func (h *Hub) Save() {
+	if h.store == nil {
+		return
+	}
+
+	for _, bot := range h.copyBots() {
+		err := h.store.SaveBot(bot.Snapshot())
+		if err != nil {
+			log.Println("cannot save bot state: ", err)
+		}
+	}
+}
+
+// Autosave saves the states of all bots every interval until the hub is closed.
+func (h *Hub) Autosave(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			h.Save()
+		case <-h.closeCh:
+			return
+		}
+	}
+}
+
+// Close stops autosaving and saves the states of all bots.
+func (h *Hub) Close() {
+	close(h.closeCh)
+	h.Save()
 }
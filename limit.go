@@ -0,0 +1,93 @@
+package pammy
+
+import (
+	"fmt"
+	"time"
+)
+
+// QueueLimit is a kind of limit on adding tracks, see Settings.
+type QueueLimit uint
+
+const (
+	QueueLimitTrackDuration QueueLimit = iota
+	QueueLimitUserTracks
+	QueueLimitQueueLength
+)
+
+// LimitError is returned when adding tracks would exceed a limit of the guild.
+type LimitError struct {
+	Limit QueueLimit
+	// Track and MaxDuration are set for QueueLimitTrackDuration.
+	Track       *Track
+	MaxDuration time.Duration
+	// MaxTracks is set for the other limits.
+	MaxTracks int
+}
+
+func (e *LimitError) Error() string {
+	switch e.Limit {
+	case QueueLimitTrackDuration:
+		if e.Track.Live {
+			return fmt.Sprintf("`%s` is a live stream but tracks are limited to %s", e.Track.Name, formatDuration(e.MaxDuration))
+		}
+		return fmt.Sprintf("`%s` is longer than the limit of %s", e.Track.Name, formatDuration(e.MaxDuration))
+	case QueueLimitUserTracks:
+		return fmt.Sprintf("You can queue up to %d tracks", e.MaxTracks)
+	case QueueLimitQueueLength:
+		return fmt.Sprintf("The queue is limited to %d tracks", e.MaxTracks)
+	default:
+		return "queue limit exceeded"
+	}
+}
+
+// checkLimits returns a *LimitError if the tracks cannot be added to the upcoming tracks, b.mu must be held.
+// Tracks without a requester (autoplay) are not counted towards the limit per user.
+func (b *Bot) checkLimits(tracks []*Track) error {
+	if b.settings.MaxTrackDuration > 0 {
+		for _, track := range tracks {
+			if track.Live || track.Duration > b.settings.MaxTrackDuration {
+				return &LimitError{
+					Limit:       QueueLimitTrackDuration,
+					Track:       track,
+					MaxDuration: b.settings.MaxTrackDuration,
+				}
+			}
+		}
+	}
+
+	start := b.upcomingIdx()
+	if start > len(b.tracks) {
+		start = len(b.tracks)
+	}
+	upcoming := b.tracks[start:]
+
+	if b.settings.MaxQueueLength > 0 && len(upcoming)+len(tracks) > b.settings.MaxQueueLength {
+		return &LimitError{
+			Limit:     QueueLimitQueueLength,
+			MaxTracks: b.settings.MaxQueueLength,
+		}
+	}
+
+	if b.settings.MaxUserTracks > 0 {
+		count := make(map[string]int)
+		for _, track := range upcoming {
+			count[track.RequesterID]++
+		}
+
+		for _, track := range tracks {
+			if track.RequesterID == "" {
+				continue
+			}
+
+			count[track.RequesterID]++
+			if count[track.RequesterID] > b.settings.MaxUserTracks {
+				return &LimitError{
+					Limit:     QueueLimitUserTracks,
+					MaxTracks: b.settings.MaxUserTracks,
+				}
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package pammy
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCheckLimits(t *testing.T) {
+	tests := []struct {
+		name      string
+		queue     []*Track
+		settings  Settings
+		tracks    []*Track
+		wantLimit QueueLimit
+		wantErr   bool
+	}{
+		{name: "no limits", queue: testTracks("a0", "a1", "a2"), tracks: testTracks("a3", "a4")},
+		{
+			name:      "too long",
+			settings:  Settings{MaxTrackDuration: 5 * time.Minute},
+			tracks:    []*Track{{ID: "a1", RequesterID: "a", Duration: 6 * time.Minute}},
+			wantLimit: QueueLimitTrackDuration,
+			wantErr:   true,
+		},
+		{
+			name:     "short enough",
+			settings: Settings{MaxTrackDuration: 5 * time.Minute},
+			tracks:   []*Track{{ID: "a1", RequesterID: "a", Duration: 5 * time.Minute}},
+		},
+		{
+			name:      "live with duration limit",
+			settings:  Settings{MaxTrackDuration: 5 * time.Minute},
+			tracks:    []*Track{{ID: "a1", RequesterID: "a", Live: true}},
+			wantLimit: QueueLimitTrackDuration,
+			wantErr:   true,
+		},
+		{
+			name:      "queue full",
+			queue:     testTracks("a0", "b1", "c1"),
+			settings:  Settings{MaxQueueLength: 3},
+			tracks:    testTracks("d1", "d2"),
+			wantLimit: QueueLimitQueueLength,
+			wantErr:   true,
+		},
+		{
+			name:     "playing track is not queued",
+			queue:    testTracks("a0", "b1", "c1"),
+			settings: Settings{MaxQueueLength: 3},
+			tracks:   testTracks("d1"),
+		},
+		{
+			name:      "too many tracks of user",
+			queue:     testTracks("a0", "a1", "b1"),
+			settings:  Settings{MaxUserTracks: 2},
+			tracks:    testTracks("a2", "a3"),
+			wantLimit: QueueLimitUserTracks,
+			wantErr:   true,
+		},
+		{
+			name:     "other user",
+			queue:    testTracks("a0", "a1", "a2"),
+			settings: Settings{MaxUserTracks: 2},
+			tracks:   testTracks("b1", "b2"),
+		},
+		{
+			name:     "autoplay is not counted",
+			queue:    []*Track{{ID: "a0", RequesterID: "a"}, {ID: "x1"}, {ID: "x2"}},
+			settings: Settings{MaxUserTracks: 1},
+			tracks:   []*Track{{ID: "x3"}},
+		},
+	}
+
+	for _, tt := range tests {
+		b := &Bot{
+			tracks:   tt.queue,
+			state:    BotStatePlaying,
+			settings: tt.settings,
+		}
+
+		err := b.checkLimits(tt.tracks)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+
+		var limitErr *LimitError
+		if err != nil && (!errors.As(err, &limitErr) || limitErr.Limit != tt.wantLimit) {
+			t.Errorf("%s: got %v, want limit %d", tt.name, err, tt.wantLimit)
+		}
+	}
+}
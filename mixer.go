@@ -0,0 +1,302 @@
+package pammy
+
+import (
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// The mixer works on 48kHz stereo signed 16-bit little-endian PCM.
+const (
+	pcmSampleRate     = 48000
+	pcmChannels       = 2
+	pcmFrameSize      = pcmChannels * 2 // bytes per sample of all channels
+	pcmBytesPerSecond = pcmSampleRate * pcmFrameSize
+)
+
+// pcmSource decodes the audio stream of a track to PCM with ffmpeg.
+type pcmSource struct {
+	track     *Track
+	title     string
+	streamURL string
+
+	// offset is the position in the track where decoding started.
+	offset time.Duration
+	// speed is the playback speed factor of the audio filters.
+	speed float64
+
+	cmd  *exec.Cmd
+	out  io.ReadCloser
+	read int64
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+func newPCMSource(track *Track, title, streamURL string, offset time.Duration, filter string, speed float64) (*pcmSource, error) {
+	args := []string{
+		"-reconnect", "1",
+		"-reconnect_streamed", "1",
+		"-reconnect_delay_max", "2",
+	}
+
+	if offset > 0 {
+		args = append(args, "-ss", strconv.FormatFloat(offset.Seconds(), 'f', 3, 64))
+	}
+
+	args = append(args,
+		"-i", streamURL,
+		"-map", "0:a",
+		"-f", "s16le",
+		"-ar", strconv.Itoa(pcmSampleRate),
+		"-ac", strconv.Itoa(pcmChannels),
+	)
+
+	if filter != "" {
+		args = append(args, "-af", filter)
+	}
+
+	args = append(args, "pipe:1")
+
+	cmd := exec.Command("ffmpeg", args...)
+	cmd.Stderr = ioutil.Discard
+
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		return nil, err
+	}
+
+	return &pcmSource{
+		track:     track,
+		title:     title,
+		streamURL: streamURL,
+		offset:    offset,
+		speed:     speed,
+		cmd:       cmd,
+		out:       out,
+	}, nil
+}
+
+func (s *pcmSource) Read(p []byte) (int, error) {
+	n, err := s.out.Read(p)
+	s.read += int64(n)
+	return n, err
+}
+
+// Position returns the position in the track that has been decoded so far.
+func (s *pcmSource) Position() time.Duration {
+	decoded := time.Duration(s.read) * time.Second / pcmBytesPerSecond
+	return s.offset + time.Duration(float64(decoded)*s.speed)
+}
+
+// Close stops decoding, it is safe to call more than once.
+func (s *pcmSource) Close() error {
+	s.closeOnce.Do(func() {
+		s.cmd.Process.Kill()
+		s.closeErr = s.cmd.Wait()
+	})
+	return s.closeErr
+}
+
+// mixer reads PCM from the playing source and applies the volume.
+// When the playing source is about to end, it opens the next source and crossfades them.
+// The next source is handed over to the next track once the playing source ends.
+type mixer struct {
+	current *pcmSource
+
+	// duration is the expected duration of the playing track.
+	duration  time.Duration
+	crossfade time.Duration
+
+	// openNext opens the source of the next track, it returns nil if there is no next track.
+	openNext func() *pcmSource
+
+	// fadeLen and fadePos are the number of samples of the crossfade and the samples that have been mixed.
+	fadeLen int
+	fadePos int
+	nextBuf []byte
+
+	// mu guards the fields below which are accessed outside of Read.
+	mu     sync.Mutex
+	next   *pcmSource
+	volume int
+	closed bool
+}
+
+func newMixer(current *pcmSource, crossfade time.Duration, volume int, openNext func() *pcmSource) *mixer {
+	return &mixer{
+		current:   current,
+		duration:  current.track.Duration,
+		crossfade: crossfade,
+		volume:    volume,
+		openNext:  openNext,
+	}
+}
+
+// Read reads the mixed PCM, it must not be called concurrently.
+func (m *mixer) Read(p []byte) (int, error) {
+	p = p[:len(p)-len(p)%pcmFrameSize]
+	if len(p) == 0 {
+		return 0, io.ErrShortBuffer
+	}
+
+	n, err := io.ReadFull(m.current, p)
+	n -= n % pcmFrameSize
+	if n == 0 {
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		return 0, err
+	}
+	p = p[:n]
+
+	m.startCrossfade()
+
+	m.mu.Lock()
+	next := m.next
+	volume := m.volume
+	m.mu.Unlock()
+
+	if next != nil {
+		m.mixNext(next, p)
+	}
+
+	applyVolume(p, volume)
+
+	return n, nil
+}
+
+// startCrossfade opens the next source when the remaining of the playing track is within the crossfade duration.
+func (m *mixer) startCrossfade() {
+	if m.openNext == nil || m.crossfade <= 0 || m.duration <= m.crossfade {
+		return
+	}
+
+	remaining := m.duration - m.current.Position()
+	if remaining > m.crossfade {
+		return
+	}
+
+	openNext := m.openNext
+	m.openNext = nil
+
+	next := openNext()
+	if next == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		next.Close()
+		return
+	}
+
+	m.next = next
+
+	// fade over the remaining samples of the playing source so that the fade completes when it ends
+	m.fadeLen = int(float64(remaining) / m.current.speed * pcmSampleRate / float64(time.Second))
+	m.fadePos = 0
+	if m.fadeLen <= 0 {
+		m.fadeLen = 1
+	}
+}
+
+// mixNext fades out the playing samples in p and fades in the samples of the next source.
+func (m *mixer) mixNext(next *pcmSource, p []byte) {
+	if cap(m.nextBuf) < len(p) {
+		m.nextBuf = make([]byte, len(p))
+	}
+	buf := m.nextBuf[:len(p)]
+
+	n, err := io.ReadFull(next, buf)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		m.mu.Lock()
+		if m.next == next {
+			m.next = nil
+			next.Close()
+		}
+		m.mu.Unlock()
+		return
+	}
+
+	for i := 0; i+pcmFrameSize <= n; i += pcmFrameSize {
+		gain := float64(m.fadePos) / float64(m.fadeLen)
+		if gain > 1 {
+			gain = 1
+		}
+
+		for c := 0; c < pcmFrameSize; c += 2 {
+			out := float64(int16(binary.LittleEndian.Uint16(p[i+c:])))
+			in := float64(int16(binary.LittleEndian.Uint16(buf[i+c:])))
+			binary.LittleEndian.PutUint16(p[i+c:], uint16(clampInt16(out*(1-gain)+in*gain)))
+		}
+
+		m.fadePos++
+	}
+}
+
+// applyVolume scales the samples by volume percent.
+func applyVolume(p []byte, volume int) {
+	if volume == 100 {
+		return
+	}
+
+	for i := 0; i+2 <= len(p); i += 2 {
+		sample := float64(int16(binary.LittleEndian.Uint16(p[i:])))
+		binary.LittleEndian.PutUint16(p[i:], uint16(clampInt16(sample*float64(volume)/100)))
+	}
+}
+
+func clampInt16(v float64) int16 {
+	if v > 32767 {
+		return 32767
+	}
+	if v < -32768 {
+		return -32768
+	}
+	return int16(v)
+}
+
+func (m *mixer) SetVolume(v int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.volume = v
+}
+
+// Handover returns the source of the next track that has been faded in, the caller is responsible for closing it.
+func (m *mixer) Handover() *pcmSource {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	next := m.next
+	m.next = nil
+
+	return next
+}
+
+// Close closes the playing source and the next source unless it has been handed over.
+func (m *mixer) Close() {
+	// killing the playing source first unblocks a pending Read
+	m.current.Close()
+
+	m.mu.Lock()
+	next := m.next
+	m.next = nil
+	m.closed = true
+	m.mu.Unlock()
+
+	if next != nil {
+		next.Close()
+	}
+}
@@ -0,0 +1,160 @@
+package pammy
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func pcm(samples ...int16) []byte {
+	p := make([]byte, len(samples)*2)
+	for i, sample := range samples {
+		binary.LittleEndian.PutUint16(p[i*2:], uint16(sample))
+	}
+	return p
+}
+
+func pcmSamples(p []byte) []int16 {
+	samples := make([]int16, len(p)/2)
+	for i := range samples {
+		samples[i] = int16(binary.LittleEndian.Uint16(p[i*2:]))
+	}
+	return samples
+}
+
+// testPCMSource returns a source reading the samples, it must not be closed since there is no ffmpeg process.
+func testPCMSource(samples ...int16) *pcmSource {
+	return &pcmSource{
+		track: &Track{},
+		speed: 1,
+		out:   ioutil.NopCloser(bytes.NewReader(pcm(samples...))),
+	}
+}
+
+func TestApplyVolume(t *testing.T) {
+	tests := []struct {
+		volume int
+		want   []int16
+	}{
+		{volume: 100, want: []int16{1000, -1000, 20000, -20000}},
+		{volume: 50, want: []int16{500, -500, 10000, -10000}},
+		{volume: 0, want: []int16{0, 0, 0, 0}},
+		{volume: 200, want: []int16{2000, -2000, 32767, -32768}},
+	}
+
+	for _, tt := range tests {
+		p := pcm(1000, -1000, 20000, -20000)
+		applyVolume(p, tt.volume)
+
+		if got := pcmSamples(p); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("volume %d: got %v, want %v", tt.volume, got, tt.want)
+		}
+	}
+}
+
+func TestClampInt16(t *testing.T) {
+	tests := []struct {
+		v    float64
+		want int16
+	}{
+		{v: 0, want: 0},
+		{v: 1234.9, want: 1234},
+		{v: -1234.9, want: -1234},
+		{v: 32767, want: 32767},
+		{v: 40000, want: 32767},
+		{v: -32768, want: -32768},
+		{v: -40000, want: -32768},
+	}
+
+	for _, tt := range tests {
+		if got := clampInt16(tt.v); got != tt.want {
+			t.Errorf("%v: got %d, want %d", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestMixNext(t *testing.T) {
+	tests := []struct {
+		name        string
+		fadePos     int
+		next        []int16
+		want        []int16
+		wantFadePos int
+	}{
+		{
+			name:        "fade from start",
+			next:        []int16{-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000},
+			want:        []int16{1000, 1000, 500, 500, 0, 0, -500, -500},
+			wantFadePos: 4,
+		},
+		{
+			name:        "fade completed",
+			fadePos:     4,
+			next:        []int16{-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000},
+			want:        []int16{-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000},
+			wantFadePos: 8,
+		},
+		{
+			name:        "next source ends",
+			fadePos:     2,
+			next:        []int16{-1000, -1000, -1000, -1000},
+			want:        []int16{0, 0, -500, -500, 1000, 1000, 1000, 1000},
+			wantFadePos: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		next := testPCMSource(tt.next...)
+		m := &mixer{next: next, fadeLen: 4, fadePos: tt.fadePos}
+
+		p := pcm(1000, 1000, 1000, 1000, 1000, 1000, 1000, 1000)
+		m.mixNext(next, p)
+
+		if got := pcmSamples(p); !reflect.DeepEqual(got, tt.want) || m.fadePos != tt.wantFadePos {
+			t.Errorf("%s: got %v at %d, want %v at %d", tt.name, got, m.fadePos, tt.want, tt.wantFadePos)
+		}
+	}
+}
+
+func TestStartCrossfade(t *testing.T) {
+	tests := []struct {
+		name        string
+		position    time.Duration
+		speed       float64
+		wantFadeLen int
+		wantNext    bool
+	}{
+		{name: "before crossfade", position: 5 * time.Second, speed: 1},
+		{name: "within crossfade", position: 8 * time.Second, speed: 1, wantFadeLen: 2 * pcmSampleRate, wantNext: true},
+		{name: "faster speed", position: 8 * time.Second, speed: 2, wantFadeLen: pcmSampleRate, wantNext: true},
+		{name: "ended", position: 10 * time.Second, speed: 1, wantFadeLen: 1, wantNext: true},
+	}
+
+	for _, tt := range tests {
+		current := testPCMSource()
+		current.offset = tt.position
+		current.speed = tt.speed
+
+		next := testPCMSource()
+		m := &mixer{
+			current:   current,
+			duration:  10 * time.Second,
+			crossfade: 3 * time.Second,
+			openNext:  func() *pcmSource { return next },
+		}
+
+		m.startCrossfade()
+
+		if (m.next != nil) != tt.wantNext || m.fadeLen != tt.wantFadeLen {
+			t.Errorf("%s: got next %v with fade length %d, want next %v with fade length %d", tt.name, m.next != nil, m.fadeLen, tt.wantNext, tt.wantFadeLen)
+		}
+
+		// the next source is opened only once
+		if (m.openNext == nil) != tt.wantNext {
+			t.Errorf("%s: next source opener is kept = %v", tt.name, m.openNext != nil)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package pammy
+
+import (
+	"github.com/bwmarrin/discordgo"
+)
+
+// djCommands are the commands that affect everyone in the guild.
+// They are restricted to DJs once the guild has a DJ role.
+var djCommands = map[string]bool{
+	"clear":     true,
+	"reset":     true,
+	"leave":     true,
+	"autoplay":  true,
+	"fairqueue": true,
+	// reordering the queue changes when the tracks of the other members play
+	"move":      true,
+	"shuffle":   true,
+	"unshuffle": true,
+}
+
+// adminCommands are restricted to members who can manage the guild.
+var adminCommands = map[string]bool{
+	"settings": true,
+}
+
+// Policy decides whether a member can run a command.
+type Policy struct {
+	hub *Hub
+}
+
+func NewPolicy(hub *Hub) *Policy {
+	return &Policy{
+		hub: hub,
+	}
+}
+
+// Allowed reports whether the member who invoked the interaction can run the command.
+func (p *Policy) Allowed(i *discordgo.InteractionCreate) bool {
+	if i.Member == nil {
+		return true
+	}
+
+	data := i.ApplicationCommandData()
+
+	switch {
+	case adminCommands[data.Name]:
+		return isGuildManager(i.Member)
+	case djCommands[data.Name]:
+		return p.isDJ(i.GuildID, i.Member)
+	case data.Name == "remove":
+		// members can remove their own tracks
+		if p.isDJ(i.GuildID, i.Member) || len(data.Options) == 0 {
+			return true
+		}
+
+		bot, ok := p.hub.GetBot(i.GuildID)
+		if !ok {
+			return true
+		}
+
+		track, ok := bot.TrackAt(int(data.Options[0].IntValue()) - 1)
+		return !ok || track.RequesterID == i.Member.User.ID
+	case data.Name == "playlist":
+		// members manage their own playlists, the playlists of the guild are shared
+		if changesGuildPlaylists(data) {
+			return p.isDJ(i.GuildID, i.Member)
+		}
+	}
+
+	return true
+}
+
+// changesGuildPlaylists reports whether the playlist command saves or deletes a playlist of the guild.
+func changesGuildPlaylists(data discordgo.ApplicationCommandInteractionData) bool {
+	if len(data.Options) == 0 {
+		return false
+	}
+
+	sub := data.Options[0]
+	if sub.Name != "save" && sub.Name != "delete" {
+		return false
+	}
+
+	for _, opt := range sub.Options {
+		if opt.Name == "scope" && opt.StringValue() == "user" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isDJ reports whether the member has the DJ role, everyone is a DJ if the guild has no DJ role.
+func (p *Policy) isDJ(guildID string, member *discordgo.Member) bool {
+	roleID := p.hub.Settings(guildID).DJRoleID
+	if roleID == "" || isGuildManager(member) {
+		return true
+	}
+
+	for _, id := range member.Roles {
+		if id == roleID {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isGuildManager(member *discordgo.Member) bool {
+	return member.Permissions&(discordgo.PermissionAdministrator|discordgo.PermissionManageServer) != 0
+}
@@ -0,0 +1,44 @@
+package pammy
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestChangesGuildPlaylists(t *testing.T) {
+	option := func(name, value string) *discordgo.ApplicationCommandInteractionDataOption {
+		return &discordgo.ApplicationCommandInteractionDataOption{
+			Name:  name,
+			Type:  discordgo.ApplicationCommandOptionString,
+			Value: value,
+		}
+	}
+
+	tests := []struct {
+		sub     string
+		options []*discordgo.ApplicationCommandInteractionDataOption
+		want    bool
+	}{
+		{sub: "save", options: []*discordgo.ApplicationCommandInteractionDataOption{option("name", "chill")}, want: true},
+		{sub: "save", options: []*discordgo.ApplicationCommandInteractionDataOption{option("name", "chill"), option("scope", "guild")}, want: true},
+		{sub: "save", options: []*discordgo.ApplicationCommandInteractionDataOption{option("name", "chill"), option("scope", "user")}, want: false},
+		{sub: "delete", options: []*discordgo.ApplicationCommandInteractionDataOption{option("name", "chill")}, want: true},
+		{sub: "delete", options: []*discordgo.ApplicationCommandInteractionDataOption{option("scope", "user"), option("name", "chill")}, want: false},
+		{sub: "load", options: []*discordgo.ApplicationCommandInteractionDataOption{option("name", "chill")}, want: false},
+		{sub: "list", want: false},
+	}
+
+	for i, tt := range tests {
+		data := discordgo.ApplicationCommandInteractionData{
+			Name: "playlist",
+			Options: []*discordgo.ApplicationCommandInteractionDataOption{
+				{Name: tt.sub, Type: discordgo.ApplicationCommandOptionSubCommand, Options: tt.options},
+			},
+		}
+
+		if got := changesGuildPlaylists(data); got != tt.want {
+			t.Errorf("%d %s: got %v, want %v", i, tt.sub, got, tt.want)
+		}
+	}
+}
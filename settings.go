@@ -0,0 +1,32 @@
+package pammy
+
+import "time"
+
+// Settings are the preferences of a guild, they are kept after the bot leaves.
+type Settings struct {
+	// Normalize enables EBU R128 loudness normalization so that tracks play at a consistent level.
+	Normalize bool
+	// VoteSkipRatio is the fraction of listeners in the voice channel needed to skip a track by voting.
+	VoteSkipRatio float64
+	// DJRoleID is the role allowed to run the commands that affect everyone, see Policy.
+	DJRoleID string
+	// FairQueue interleaves the upcoming tracks by requester when tracks are added.
+	FairQueue bool
+
+	// The limits on adding tracks, zero means no limit. See LimitError.
+	// MaxUserTracks is the maximum upcoming tracks requested by a member.
+	MaxUserTracks int
+	// MaxTrackDuration is the maximum duration of a track, it also applies to autoplay.
+	MaxTrackDuration time.Duration
+	// MaxQueueLength is the maximum upcoming tracks.
+	MaxQueueLength int
+}
+
+func DefaultSettings() Settings {
+	return Settings{
+		VoteSkipRatio: 0.5,
+	}
+}
+
+// loudnormFilter normalizes to the integrated loudness recommended for streaming.
+const loudnormFilter = "loudnorm=I=-16:TP=-1.5:LRA=11"
@@ -0,0 +1,237 @@
+package pammy
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrPlaylistNotFound    = errors.New("playlist not found")
+	ErrInvalidPlaylistName = errors.New("invalid playlist name")
+)
+
+// maxPlaylistFileName is the maximum length of an escaped playlist name.
+// It keeps the file name and the temporary file written before it under the limit of 255 bytes of most file systems.
+const maxPlaylistFileName = 200
+
+// PlaylistScope is the owner of saved playlists, either a guild or a user.
+type PlaylistScope struct {
+	GuildID string
+	UserID  string
+}
+
+func GuildPlaylistScope(guildID string) PlaylistScope {
+	return PlaylistScope{GuildID: guildID}
+}
+
+func UserPlaylistScope(userID string) PlaylistScope {
+	return PlaylistScope{UserID: userID}
+}
+
+func (s PlaylistScope) dir() string {
+	if s.UserID != "" {
+		return filepath.Join("users", s.UserID)
+	}
+	return filepath.Join("guilds", s.GuildID)
+}
+
+type SavedPlaylist struct {
+	Name   string
+	Tracks []*Track
+}
+
+// Store persists the bot states and saved playlists as JSON files in a directory.
+type Store struct {
+	dir string
+}
+
+func NewStore(dir string) (*Store, error) {
+	for _, sub := range []string{"bots", "settings"} {
+		err := os.MkdirAll(filepath.Join(dir, sub), 0o755)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Store{
+		dir: dir,
+	}, nil
+}
+
+func (s *Store) SaveBot(snapshot BotSnapshot) error {
+	return writeJSONFile(s.botPath(snapshot.GuildID), snapshot)
+}
+
+func (s *Store) LoadBots() ([]BotSnapshot, error) {
+	files, err := filepath.Glob(filepath.Join(s.dir, "bots", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []BotSnapshot
+	for _, file := range files {
+		var snapshot BotSnapshot
+		err := readJSONFile(file, &snapshot)
+		if err != nil {
+			return nil, err
+		}
+
+		snapshots = append(snapshots, snapshot)
+	}
+
+	return snapshots, nil
+}
+
+func (s *Store) DeleteBot(guildID string) error {
+	err := os.Remove(s.botPath(guildID))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Store) botPath(guildID string) string {
+	return filepath.Join(s.dir, "bots", guildID+".json")
+}
+
+func (s *Store) SaveSettings(guildID string, settings Settings) error {
+	return writeJSONFile(s.settingsPath(guildID), settings)
+}
+
+// LoadSettings returns the settings of the guild, the default settings are returned if the guild has none.
+func (s *Store) LoadSettings(guildID string) (Settings, error) {
+	settings := DefaultSettings()
+
+	err := readJSONFile(s.settingsPath(guildID), &settings)
+	if os.IsNotExist(err) {
+		return DefaultSettings(), nil
+	}
+
+	return settings, err
+}
+
+func (s *Store) settingsPath(guildID string) string {
+	return filepath.Join(s.dir, "settings", guildID+".json")
+}
+
+func (s *Store) SavePlaylist(scope PlaylistScope, playlist SavedPlaylist) error {
+	path, err := s.playlistPath(scope, playlist.Name)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return err
+	}
+
+	return writeJSONFile(path, playlist)
+}
+
+func (s *Store) LoadPlaylist(scope PlaylistScope, name string) (SavedPlaylist, error) {
+	path, err := s.playlistPath(scope, name)
+	if err != nil {
+		return SavedPlaylist{}, err
+	}
+
+	var playlist SavedPlaylist
+	err = readJSONFile(path, &playlist)
+	if os.IsNotExist(err) {
+		return SavedPlaylist{}, ErrPlaylistNotFound
+	}
+
+	return playlist, err
+}
+
+// ListPlaylists returns the names of the saved playlists in alphabetical order.
+func (s *Store) ListPlaylists(scope PlaylistScope) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(s.dir, "playlists", scope.dir(), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, file := range files {
+		name, err := url.PathUnescape(strings.TrimSuffix(filepath.Base(file), ".json"))
+		if err != nil {
+			continue
+		}
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+func (s *Store) DeletePlaylist(scope PlaylistScope, name string) error {
+	path, err := s.playlistPath(scope, name)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if os.IsNotExist(err) {
+		return ErrPlaylistNotFound
+	}
+
+	return err
+}
+
+func (s *Store) playlistPath(scope PlaylistScope, name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 100 || strings.HasPrefix(name, ".") {
+		return "", ErrInvalidPlaylistName
+	}
+
+	// non-ASCII characters are escaped to several bytes each
+	fileName := url.PathEscape(name)
+	if len(fileName) > maxPlaylistFileName {
+		return "", ErrInvalidPlaylistName
+	}
+
+	return filepath.Join(s.dir, "playlists", scope.dir(), fileName+".json"), nil
+}
+
+func readJSONFile(path string, v interface{}) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// writeJSONFile writes to a temporary file then renames it so that a crash never leaves a partial file.
+func writeJSONFile(path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+strings.TrimSuffix(filepath.Base(path), ".json")+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(data)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,151 @@
+package pammy
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStoreBots(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot := BotSnapshot{
+		GuildID:        "1",
+		VoiceChannelID: "2",
+		TextChannelID:  "3",
+		Tracks: []*Track{
+			{ID: "a", Name: "A", Duration: time.Minute, RequesterID: "4", RequesterName: "user", RequestedAt: time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC), Source: TrackSourceManual},
+			{ID: "b", Name: "B", Live: true, Source: TrackSourceAutoplay},
+		},
+		CurrentTrackIdx: 1,
+		Playing:         true,
+		Position:        30 * time.Second,
+		LoopMode:        LoopModeQueue,
+		Volume:          80,
+		Filters:         []string{"bassboost"},
+		Crossfade:       3 * time.Second,
+	}
+
+	err = store.SaveBot(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := store.LoadBots()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []BotSnapshot{snapshot}; !reflect.DeepEqual(got, want) {
+		t.Errorf("\nwant: %+v\ngot: %+v", want, got)
+	}
+
+	err = store.DeleteBot(snapshot.GuildID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err = store.LoadBots()
+	if err != nil || len(got) != 0 {
+		t.Errorf("got %+v, %v after deleting", got, err)
+	}
+
+	if err := store.DeleteBot(snapshot.GuildID); err != nil {
+		t.Errorf("deleting twice: %v", err)
+	}
+}
+
+func TestStoreSettings(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := store.LoadSettings("1")
+	if err != nil || got != DefaultSettings() {
+		t.Errorf("got %+v, %v without saved settings, want defaults", got, err)
+	}
+
+	settings := DefaultSettings()
+	settings.Normalize = true
+	settings.MaxTrackDuration = 10 * time.Minute
+
+	err = store.SaveSettings("1", settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err = store.LoadSettings("1")
+	if err != nil || got != settings {
+		t.Errorf("got %+v, %v, want %+v", got, err, settings)
+	}
+}
+
+func TestStorePlaylists(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr error
+	}{
+		{name: "chill"},
+		{name: "lo-fi / study"},
+		{name: "a/../b"},
+		{name: "100%"},
+		{name: "เพลงเพราะ"},
+		{name: "", wantErr: ErrInvalidPlaylistName},
+		{name: "   ", wantErr: ErrInvalidPlaylistName},
+		{name: ".hidden", wantErr: ErrInvalidPlaylistName},
+		{name: "../escape", wantErr: ErrInvalidPlaylistName},
+		{name: strings.Repeat("a", 101), wantErr: ErrInvalidPlaylistName},
+		{name: strings.Repeat("ก", 22)},
+		{name: strings.Repeat("ก", 23), wantErr: ErrInvalidPlaylistName},
+	}
+
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	scope := UserPlaylistScope("1")
+
+	var saved []string
+	for _, tt := range tests {
+		playlist := SavedPlaylist{Name: tt.name, Tracks: []*Track{{ID: "a", Name: "A"}}}
+
+		err := store.SavePlaylist(scope, playlist)
+		if err != tt.wantErr {
+			t.Errorf("%q: save error = %v, want %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		saved = append(saved, tt.name)
+
+		got, err := store.LoadPlaylist(scope, tt.name)
+		if err != nil || !reflect.DeepEqual(got, playlist) {
+			t.Errorf("%q: got %+v, %v, want %+v", tt.name, got, err, playlist)
+		}
+	}
+
+	want := []string{"100%", "a/../b", "chill", "lo-fi / study", strings.Repeat("ก", 22), "เพลงเพราะ"}
+	if got, err := store.ListPlaylists(scope); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("list: got %q, %v, want %q", got, err, want)
+	}
+
+	if got, err := store.ListPlaylists(GuildPlaylistScope("1")); err != nil || len(got) != 0 {
+		t.Errorf("list of guild: got %q, %v, want none", got, err)
+	}
+
+	for _, name := range saved {
+		if err := store.DeletePlaylist(scope, name); err != nil {
+			t.Errorf("%q: delete error = %v", name, err)
+		}
+	}
+
+	if _, err := store.LoadPlaylist(scope, "chill"); err != ErrPlaylistNotFound {
+		t.Errorf("load deleted: error = %v, want %v", err, ErrPlaylistNotFound)
+	}
+}
@@ -1,12 +1,16 @@
 package youtube
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -17,6 +21,17 @@ import (
 
 var ErrNotFound = errors.New("not found")
 
+// maxPlaylistPages is the number of pages of about 100 videos that are loaded from a playlist.
+const maxPlaylistPages = 50
+
+var (
+	playlistIDRegex    = regexp.MustCompile("^[A-Za-z0-9_-]{18,42}$")
+	playlistInURLRegex = regexp.MustCompile("[&?]list=([A-Za-z0-9_-]{18,42})(&.*)?$")
+
+	innertubeAPIKeyRegex        = regexp.MustCompile(`"INNERTUBE_API_KEY":"([^"]+)"`)
+	innertubeClientVersionRegex = regexp.MustCompile(`"INNERTUBE_CLIENT_VERSION":"([^"]+)"`)
+)
+
 type Client struct {
 	*youtube.Client
 
@@ -27,6 +42,22 @@ type VideoInfo struct {
 	ID       string
 	Title    string
 	Duration time.Duration
+	// Live is true for live streams, their duration is unknown.
+	Live bool
+}
+
+type Playlist struct {
+	ID     string
+	Title  string
+	Videos []VideoInfo
+	// Truncated is true if only the first videos of the playlist could be loaded.
+	Truncated bool
+}
+
+// innertubeConfig is what the YouTube web client sends to load more results, e.g. the next videos of a playlist.
+type innertubeConfig struct {
+	apiKey        string
+	clientVersion string
 }
 
 func NewClient() *Client {
@@ -83,8 +114,132 @@ func (c *Client) GetSuggestedVideos(id string) ([]VideoInfo, error) {
 	return infos, nil
 }
 
-func (c *Client) GetAudioStreamURL(video *youtube.Video) (string, error) {
-	format := c.filterAudioChannel(video.Formats)
+// GetPlaylist returns the playlist and its videos from a playlist URL or ID.
+// The playlist page has about 100 videos, the rest are loaded page by page through its continuations.
+// Truncated is set if they cannot be loaded or there are more than maxPlaylistPages pages.
+func (c *Client) GetPlaylist(url string) (Playlist, error) {
+	id, err := extractPlaylistID(url)
+	if err != nil {
+		return Playlist{}, err
+	}
+
+	resp, err := c.httpClient.Get("https://www.youtube.com/playlist?hl=en&list=" + id)
+	if err != nil {
+		return Playlist{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Playlist{}, fmt.Errorf("invalid response code: %d", resp.StatusCode)
+	}
+
+	page, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return Playlist{}, err
+	}
+
+	playlist, continuation, err := ExtractPlaylist(bytes.NewReader(page))
+	if err != nil {
+		return Playlist{}, err
+	}
+	playlist.ID = id
+
+	config := extractInnertubeConfig(page)
+	for pages := 1; continuation != ""; pages++ {
+		if pages == maxPlaylistPages || config.apiKey == "" || config.clientVersion == "" {
+			playlist.Truncated = true
+			break
+		}
+
+		var videos []VideoInfo
+		videos, continuation, err = c.getPlaylistContinuation(config, continuation)
+		if err != nil {
+			playlist.Truncated = true
+			break
+		}
+
+		playlist.Videos = append(playlist.Videos, videos...)
+	}
+
+	return playlist, nil
+}
+
+// getPlaylistContinuation returns the next videos of a playlist and the continuation of the videos after them.
+func (c *Client) getPlaylistContinuation(config innertubeConfig, continuation string) ([]VideoInfo, string, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"context": map[string]interface{}{
+			"client": map[string]string{
+				"clientName":    "WEB",
+				"clientVersion": config.clientVersion,
+				"hl":            "en",
+			},
+		},
+		"continuation": continuation,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	resp, err := c.httpClient.Post("https://www.youtube.com/youtubei/v1/browse?key="+url.QueryEscape(config.apiKey), "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("invalid response code: %d", resp.StatusCode)
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	videos, next := ExtractPlaylistContinuation(data)
+
+	return videos, next, nil
+}
+
+func extractPlaylistID(url string) (string, error) {
+	if playlistIDRegex.MatchString(url) {
+		return url, nil
+	}
+
+	matches := playlistInURLRegex.FindStringSubmatch(url)
+	if matches == nil {
+		return "", fmt.Errorf("invalid playlist: %q", url)
+	}
+
+	return matches[1], nil
+}
+
+func extractInnertubeConfig(page []byte) innertubeConfig {
+	var config innertubeConfig
+
+	if matches := innertubeAPIKeyRegex.FindSubmatch(page); matches != nil {
+		config.apiKey = string(matches[1])
+	}
+
+	if matches := innertubeClientVersionRegex.FindSubmatch(page); matches != nil {
+		config.clientVersion = string(matches[1])
+	}
+
+	return config
+}
+
+// IsLive reports whether the video is a live stream (including a premiere that has started).
+func IsLive(video *youtube.Video) bool {
+	return video.HLSManifestURL != ""
+}
+
+// GetAudioStreamURL returns the URL of the audio stream of the video, it is an HLS playlist for live streams.
+// maxBitrate is the bitrate in bits per second that the audio can be played at, 0 means no limit.
+func (c *Client) GetAudioStreamURL(video *youtube.Video, maxBitrate int) (string, error) {
+	if IsLive(video) {
+		return c.getLiveStreamURL(video, maxBitrate)
+	}
+
+	format := c.filterAudioChannel(video.Formats, maxBitrate)
 	if format == nil {
 		return "", errors.New("no audio format")
 	}
@@ -119,17 +274,135 @@ func (c *Client) GetAudioStreamURL(video *youtube.Video) (string, error) {
 	return streamURL, lastErr
 }
 
-var opusItags = [...]int{249, 250, 251}
+// getLiveStreamURL returns the URL of a variant playlist in the HLS manifest of the live stream.
+// Every variant is muxed with video, so choosing one avoids downloading all of them.
+func (c *Client) getLiveStreamURL(video *youtube.Video, maxBitrate int) (string, error) {
+	resp, err := c.httpClient.Get(video.HLSManifestURL)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("invalid response code: %d", resp.StatusCode)
+	}
+
+	manifestURL, err := url.Parse(video.HLSManifestURL)
+	if err != nil {
+		return "", err
+	}
+
+	return selectHLSVariant(resp.Body, manifestURL, maxBitrate)
+}
 
-func (c *Client) filterAudioChannel(formats youtube.FormatList) *youtube.Format {
-	for _, itag := range opusItags {
-		format := formats.FindByItag(itag)
-		if format != nil {
+// selectHLSVariant returns the URL of the variant in the HLS master playlist with the bitrate chosen like filterAudioChannel.
+// The manifest URL is returned if it is a media playlist without variants.
+func selectHLSVariant(r io.Reader, manifestURL *url.URL, maxBitrate int) (string, error) {
+	var (
+		variants  []*youtube.Format
+		bandwidth = -1
+	)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case strings.HasPrefix(line, "#EXT-X-STREAM-INF:"):
+			bandwidth = 0
+			for _, attr := range strings.Split(strings.TrimPrefix(line, "#EXT-X-STREAM-INF:"), ",") {
+				if strings.HasPrefix(attr, "BANDWIDTH=") {
+					bandwidth, _ = strconv.Atoi(strings.TrimPrefix(attr, "BANDWIDTH="))
+				}
+			}
+		case line == "" || strings.HasPrefix(line, "#"):
+		case bandwidth >= 0:
+			uri, err := manifestURL.Parse(line)
+			if err != nil {
+				return "", err
+			}
+
+			variants = append(variants, &youtube.Format{URL: uri.String(), Bitrate: bandwidth})
+			bandwidth = -1
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	if len(variants) == 0 {
+		return manifestURL.String(), nil
+	}
+
+	return bestBitrate(variants, maxBitrate).URL, nil
+}
+
+var (
+	opusItags = []int{249, 250, 251}
+	aacItags  = []int{139, 140}
+)
+
+// filterAudioChannel selects the audio format to stream, opus is preferred since it is what Discord plays.
+// It falls back to AAC and then to the formats muxed with video.
+// Within the same codec, the highest bitrate up to maxBitrate is preferred, or the lowest if none fits.
+// Set maxBitrate to 0 for no limit.
+func (c *Client) filterAudioChannel(formats youtube.FormatList, maxBitrate int) *youtube.Format {
+	for _, itags := range [][]int{opusItags, aacItags} {
+		var candidates []*youtube.Format
+		for _, itag := range itags {
+			format := formats.FindByItag(itag)
+			if format != nil {
+				candidates = append(candidates, format)
+			}
+		}
+
+		if format := bestBitrate(candidates, maxBitrate); format != nil {
 			return format
 		}
 	}
 
-	return nil
+	var muxed []*youtube.Format
+	for i := range formats {
+		if strings.HasPrefix(formats[i].MimeType, "video/") && formats[i].AudioChannels > 0 {
+			muxed = append(muxed, &formats[i])
+		}
+	}
+
+	return bestBitrate(muxed, maxBitrate)
+}
+
+// bestBitrate returns the format with the highest bitrate up to maxBitrate, or the lowest bitrate if none fits.
+func bestBitrate(formats []*youtube.Format, maxBitrate int) *youtube.Format {
+	var best, lowest *youtube.Format
+	for _, format := range formats {
+		bitrate := formatBitrate(format)
+
+		if lowest == nil || bitrate < formatBitrate(lowest) {
+			lowest = format
+		}
+
+		if maxBitrate > 0 && bitrate > maxBitrate {
+			continue
+		}
+
+		if best == nil || bitrate > formatBitrate(best) {
+			best = format
+		}
+	}
+
+	if best == nil {
+		return lowest
+	}
+
+	return best
+}
+
+func formatBitrate(format *youtube.Format) int {
+	if format.AverageBitrate > 0 {
+		return format.AverageBitrate
+	}
+	return format.Bitrate
 }
 
 func extractJSONData(r io.Reader) ([]byte, error) {
@@ -170,6 +443,7 @@ func ExtractSearchResult(r io.Reader) ([]VideoInfo, error) {
 			ID:       value.Get("videoId").Str,
 			Title:    value.Get("title.runs.0.text").Str,
 			Duration: durationFromLengthText(value.Get("lengthText.simpleText").Str),
+			Live:     isLiveNow(value),
 		}
 
 		infos = append(infos, info)
@@ -193,6 +467,7 @@ func ExtractSuggestedVideos(r io.Reader) ([]VideoInfo, error) {
 			ID:       value.Get("videoId").Str,
 			Title:    value.Get("title.simpleText").Str,
 			Duration: durationFromLengthText(value.Get("lengthText.simpleText").Str),
+			Live:     isLiveNow(value),
 		}
 
 		infos = append(infos, info)
@@ -202,23 +477,105 @@ func ExtractSuggestedVideos(r io.Reader) ([]VideoInfo, error) {
 	return infos, nil
 }
 
+// isLiveNow reports whether the video renderer has the badge of a live stream.
+func isLiveNow(renderer gjson.Result) bool {
+	for _, style := range renderer.Get("badges.#.metadataBadgeRenderer.style").Array() {
+		if style.Str == "BADGE_STYLE_TYPE_LIVE_NOW" {
+			return true
+		}
+	}
+	return false
+}
+
 func durationFromLengthText(text string) time.Duration {
+	d, err := ParseLengthText(text)
+	if err != nil {
+		return 0
+	}
+
+	return d
+}
+
+// ParseLengthText parses a length text in the format of `s`, `m:ss` or `h:mm:ss` (e.g. `1:23`).
+func ParseLengthText(text string) (time.Duration, error) {
 	parts := strings.Split(text, ":")
 
 	if len(parts) > 3 {
-		return 0
+		return 0, fmt.Errorf("invalid length text: %q", text)
 	}
 
 	var d time.Duration
 	units := [3]time.Duration{time.Second, time.Minute, time.Hour}
 	for i := 0; i < len(parts); i++ {
 		n, err := strconv.Atoi(parts[len(parts)-1-i])
-		if err != nil {
-			return 0
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid length text: %q", text)
 		}
 
 		d += time.Duration(n) * units[i]
 	}
 
-	return d
+	return d, nil
+}
+
+// ExtractPlaylist returns the playlist on a playlist page and the continuation of the videos after the first page.
+// The continuation is empty if the page has all videos of the playlist.
+func ExtractPlaylist(r io.Reader) (Playlist, string, error) {
+	data, err := extractJSONData(r)
+	if err != nil {
+		return Playlist{}, "", err
+	}
+
+	items := gjson.GetBytes(data, "contents.twoColumnBrowseResultsRenderer.tabs.0.tabRenderer.content.sectionListRenderer.contents.0.itemSectionRenderer.contents.0.playlistVideoListRenderer.contents")
+	if !items.Exists() {
+		return Playlist{}, "", ErrNotFound
+	}
+
+	videos, continuation := extractPlaylistItems(items)
+
+	playlist := Playlist{
+		Title:  gjson.GetBytes(data, "metadata.playlistMetadataRenderer.title").Str,
+		Videos: videos,
+	}
+
+	return playlist, continuation, nil
+}
+
+// ExtractPlaylistContinuation returns the videos in a response of loading a playlist continuation
+// and the continuation of the videos after them.
+func ExtractPlaylistContinuation(data []byte) ([]VideoInfo, string) {
+	items := gjson.GetBytes(data, "onResponseReceivedActions.0.appendContinuationItemsAction.continuationItems")
+	return extractPlaylistItems(items)
+}
+
+// extractPlaylistItems returns the videos of the playlist items and the continuation in the last item, if any.
+// Deleted and private videos are left out since they cannot be played.
+func extractPlaylistItems(items gjson.Result) ([]VideoInfo, string) {
+	var (
+		infos        []VideoInfo
+		continuation string
+	)
+
+	items.ForEach(func(key, value gjson.Result) bool {
+		if token := value.Get("continuationItemRenderer.continuationEndpoint.continuationCommand.token"); token.Exists() {
+			continuation = token.Str
+			return true
+		}
+
+		renderer := value.Get("playlistVideoRenderer")
+		if !renderer.Exists() || renderer.Get("isPlayable").Exists() && !renderer.Get("isPlayable").Bool() {
+			return true
+		}
+
+		seconds, _ := strconv.Atoi(renderer.Get("lengthSeconds").Str)
+
+		infos = append(infos, VideoInfo{
+			ID:       renderer.Get("videoId").Str,
+			Title:    renderer.Get("title.runs.0.text").Str,
+			Duration: time.Duration(seconds) * time.Second,
+		})
+		return true
+	})
+
+	return infos, continuation
 }
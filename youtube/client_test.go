@@ -1,9 +1,14 @@
 package youtube
 
 import (
+	"net/url"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/kkdai/youtube/v2"
 )
 
 func Test_ExtractSearchResult(t *testing.T) {
@@ -35,3 +40,215 @@ func TestExtractSuggestedVideos(t *testing.T) {
 		t.Errorf("\nwant: %+v\ngot: %+v", want, got)
 	}
 }
+
+func TestParseLengthText(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    time.Duration
+		wantErr bool
+	}{
+		{text: "45", want: 45 * time.Second},
+		{text: "1:23", want: time.Minute + 23*time.Second},
+		{text: "1:02:03", want: time.Hour + 2*time.Minute + 3*time.Second},
+		{text: "", wantErr: true},
+		{text: "1:-2", wantErr: true},
+		{text: "1:2:3:4", wantErr: true},
+		{text: "abc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseLengthText(tt.text)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseLengthText(%q) error = %v, wantErr %v", tt.text, err, tt.wantErr)
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("ParseLengthText(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestFilterAudioChannel(t *testing.T) {
+	opus := youtube.FormatList{
+		{ItagNo: 249, MimeType: `audio/webm; codecs="opus"`, AverageBitrate: 50000, AudioChannels: 2},
+		{ItagNo: 250, MimeType: `audio/webm; codecs="opus"`, AverageBitrate: 70000, AudioChannels: 2},
+		{ItagNo: 251, MimeType: `audio/webm; codecs="opus"`, AverageBitrate: 130000, AudioChannels: 2},
+	}
+	aac := youtube.FormatList{
+		{ItagNo: 139, MimeType: `audio/mp4; codecs="mp4a.40.5"`, AverageBitrate: 48000, AudioChannels: 2},
+		{ItagNo: 140, MimeType: `audio/mp4; codecs="mp4a.40.2"`, AverageBitrate: 128000, AudioChannels: 2},
+	}
+	muxed := youtube.FormatList{
+		{ItagNo: 137, MimeType: `video/mp4; codecs="avc1.640028"`, Bitrate: 4000000},
+		{ItagNo: 22, MimeType: `video/mp4; codecs="avc1.64001F, mp4a.40.2"`, Bitrate: 1500000, AudioChannels: 2},
+		{ItagNo: 18, MimeType: `video/mp4; codecs="avc1.42001E, mp4a.40.2"`, Bitrate: 500000, AudioChannels: 2},
+	}
+
+	concat := func(lists ...youtube.FormatList) youtube.FormatList {
+		var formats youtube.FormatList
+		for _, list := range lists {
+			formats = append(formats, list...)
+		}
+		return formats
+	}
+
+	tests := []struct {
+		name       string
+		formats    youtube.FormatList
+		maxBitrate int
+		want       int
+	}{
+		{name: "highest opus without limit", formats: concat(opus, aac, muxed), want: 251},
+		{name: "highest opus that fits", formats: concat(opus, aac, muxed), maxBitrate: 96000, want: 250},
+		{name: "lowest opus when none fits", formats: concat(opus, aac), maxBitrate: 8000, want: 249},
+		{name: "aac without opus", formats: concat(aac, muxed), maxBitrate: 64000, want: 139},
+		{name: "highest aac without limit", formats: concat(aac, muxed), want: 140},
+		{name: "muxed without audio only formats", formats: muxed, maxBitrate: 64000, want: 18},
+		{name: "highest muxed without limit", formats: muxed, want: 22},
+		{name: "no audio", formats: muxed[:1], want: 0},
+	}
+
+	var c Client
+	for _, tt := range tests {
+		got := c.filterAudioChannel(tt.formats, tt.maxBitrate)
+
+		var gotItag int
+		if got != nil {
+			gotItag = got.ItagNo
+		}
+
+		if gotItag != tt.want {
+			t.Errorf("%s: got itag %d, want %d", tt.name, gotItag, tt.want)
+		}
+	}
+}
+
+func TestSelectHLSVariant(t *testing.T) {
+	master := `#EXTM3U
+#EXT-X-INDEPENDENT-SEGMENTS
+#EXT-X-STREAM-INF:BANDWIDTH=290288,CODECS="mp4a.40.5,avc1.42c00b",RESOLUTION=256x144,FRAME-RATE=30
+https://manifest.googlevideo.com/api/manifest/hls_playlist/itag/91/index.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=546239,CODECS="mp4a.40.5,avc1.4d4015",RESOLUTION=426x240,FRAME-RATE=30
+https://manifest.googlevideo.com/api/manifest/hls_playlist/itag/92/index.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=1209862,CODECS="mp4a.40.2,avc1.4d401e",RESOLUTION=640x360,FRAME-RATE=30
+itag/93/index.m3u8
+`
+	media := `#EXTM3U
+#EXT-X-TARGETDURATION:5
+#EXTINF:5.0,
+segment/1.ts
+`
+
+	manifestURL, _ := url.Parse("https://manifest.googlevideo.com/api/manifest/hls_variant/id/abc/file/index.m3u8")
+
+	tests := []struct {
+		name       string
+		playlist   string
+		maxBitrate int
+		want       string
+	}{
+		{name: "highest without limit", playlist: master, want: "https://manifest.googlevideo.com/api/manifest/hls_variant/id/abc/file/itag/93/index.m3u8"},
+		{name: "highest that fits", playlist: master, maxBitrate: 600000, want: "https://manifest.googlevideo.com/api/manifest/hls_playlist/itag/92/index.m3u8"},
+		{name: "lowest when none fits", playlist: master, maxBitrate: 64000, want: "https://manifest.googlevideo.com/api/manifest/hls_playlist/itag/91/index.m3u8"},
+		{name: "media playlist", playlist: media, want: manifestURL.String()},
+	}
+
+	for _, tt := range tests {
+		got, err := selectHLSVariant(strings.NewReader(tt.playlist), manifestURL, tt.maxBitrate)
+		if err != nil {
+			t.Errorf("%s: error = %v", tt.name, err)
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestExtractPlaylist(t *testing.T) {
+	video := func(id, title, seconds string) string {
+		return `{"playlistVideoRenderer":{"videoId":"` + id + `","title":{"runs":[{"text":"` + title + `"}]},"lengthSeconds":"` + seconds + `","isPlayable":true}}`
+	}
+	deleted := `{"playlistVideoRenderer":{"videoId":"deleted","title":{"runs":[{"text":"[Deleted video]"}]},"isPlayable":false}}`
+	continuation := `{"continuationItemRenderer":{"continuationEndpoint":{"continuationCommand":{"token":"next-page"}}}}`
+	page := func(items ...string) string {
+		return `<html><script>var ytInitialData = {"metadata":{"playlistMetadataRenderer":{"title":"Mix"}},"contents":{"twoColumnBrowseResultsRenderer":{"tabs":[{"tabRenderer":{"content":{"sectionListRenderer":{"contents":[{"itemSectionRenderer":{"contents":[{"playlistVideoListRenderer":{"contents":[` + strings.Join(items, ",") + `]}}]}}]}}}}]}}};</script></html>`
+	}
+
+	tests := []struct {
+		name             string
+		page             string
+		want             Playlist
+		wantContinuation string
+		wantErr          bool
+	}{
+		{
+			name: "single page",
+			page: page(video("a", "A", "61"), deleted, video("b", "B", "5")),
+			want: Playlist{Title: "Mix", Videos: []VideoInfo{
+				{ID: "a", Title: "A", Duration: 61 * time.Second},
+				{ID: "b", Title: "B", Duration: 5 * time.Second},
+			}},
+		},
+		{
+			name:             "more pages",
+			page:             page(video("a", "A", "61"), continuation),
+			want:             Playlist{Title: "Mix", Videos: []VideoInfo{{ID: "a", Title: "A", Duration: 61 * time.Second}}},
+			wantContinuation: "next-page",
+		},
+		{
+			name:    "not a playlist",
+			page:    `<html><script>var ytInitialData = {"contents":{}};</script></html>`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		got, gotContinuation, err := ExtractPlaylist(strings.NewReader(tt.page))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+
+		if gotContinuation != tt.wantContinuation {
+			t.Errorf("%s: got continuation %q, want %q", tt.name, gotContinuation, tt.wantContinuation)
+		}
+	}
+
+	data := `{"onResponseReceivedActions":[{"appendContinuationItemsAction":{"continuationItems":[` + video("c", "C", "7") + `]}}]}`
+	videos, next := ExtractPlaylistContinuation([]byte(data))
+	if want := []VideoInfo{{ID: "c", Title: "C", Duration: 7 * time.Second}}; !reflect.DeepEqual(videos, want) || next != "" {
+		t.Errorf("continuation: got %+v %q, want %+v", videos, next, want)
+	}
+}
+
+func TestExtractPlaylistID(t *testing.T) {
+	tests := []struct {
+		url     string
+		want    string
+		wantErr bool
+	}{
+		{url: "PLFgquLnL59alCl_2TQvOiD5Vgm1hCaGSI", want: "PLFgquLnL59alCl_2TQvOiD5Vgm1hCaGSI"},
+		{url: "https://www.youtube.com/playlist?list=PLFgquLnL59alCl_2TQvOiD5Vgm1hCaGSI", want: "PLFgquLnL59alCl_2TQvOiD5Vgm1hCaGSI"},
+		{url: "https://www.youtube.com/watch?v=dQw4w9WgXcQ&list=PLFgquLnL59alCl_2TQvOiD5Vgm1hCaGSI&index=2", want: "PLFgquLnL59alCl_2TQvOiD5Vgm1hCaGSI"},
+		{url: "https://www.youtube.com/watch?v=dQw4w9WgXcQ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := extractPlaylistID(tt.url)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, wantErr %v", tt.url, err, tt.wantErr)
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.url, got, tt.want)
+		}
+	}
+}